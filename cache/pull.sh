@@ -1,8 +1,17 @@
 #!/bin/bash
+set -e
 cd "$(dirname "$0")"
 
-# sudo apt install p7zip-full
-wget https://github.com/igrek51/connect4endgames/raw/cache/cache/cache_7x6.protobuf.7z.001
-7z x cache_7x6.protobuf.7z.001
-rm cache_7x6.protobuf.7z.001
+MIRROR=${MIRROR:-https://github.com/igrek51/connect4endgames/raw/cache/cache}
+if wget -q --spider "$MIRROR/SHA256SUMS"; then
+	# gzipped parts with SHA256SUMS manifest, as created by cache/push.sh
+	[ -x ../c4solver ] || (cd .. && ./build.sh)
+	../c4solver --size 7x6 cache import "$MIRROR/" -o cache_7x6.protobuf
+else
+	# mirror hasn't been republished in the new format yet
+	# sudo apt install p7zip-full
+	wget "$MIRROR/cache_7x6.protobuf.7z.001"
+	7z x cache_7x6.protobuf.7z.001
+	rm cache_7x6.protobuf.7z.001
+fi
 echo "Cached endgames ready"
@@ -3,6 +3,13 @@ cd "$(dirname "$0")"
 
 # sudo apt install p7zip-full
 7z -v99m a cache_7x6.protobuf.7z cache_7x6.protobuf
+
+# gzipped parts with checksum manifest, importable with: ./c4solver cache import <mirror>
+gzip -9 -k -f cache_7x6.protobuf
+split -d -a 3 --numeric-suffixes=1 -b 99m cache_7x6.protobuf.gz cache_7x6.protobuf.gz.
+rm cache_7x6.protobuf.gz
+sha256sum cache_7x6.protobuf.gz.* > SHA256SUMS
+
 # copy to https://github.com/igrek51/connect4endgames
 # git checkout --orphan cache
-# git add cache/cache_7x6.protobuf.7z.001
+# git add cache/cache_7x6.protobuf.7z.001 cache/cache_7x6.protobuf.gz.* cache/SHA256SUMS
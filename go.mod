@@ -10,6 +10,7 @@ require (
 	github.com/schollz/progressbar/v3 v3.7.6
 	github.com/stretchr/testify v1.7.0
 	golang.org/x/sys v0.0.0-20210326220804-49726bf1d181 // indirect
+	golang.org/x/term v0.0.0-20210220032956-6a3ed077a48d
 	golang.org/x/text v0.3.6
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 	google.golang.org/protobuf v1.26.0
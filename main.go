@@ -19,13 +19,46 @@ func main() {
 		pprof.StartCPUProfile(cpuProfile)
 		defer pprof.StopCPUProfile()
 	}
+	if args.Metrics != nil {
+		if err := c4.StartMetricsServer(args.MetricsAddr, args.Metrics); err != nil {
+			log.Crit("Starting metrics server failed", log.Ctx{"error": err})
+			os.Exit(1)
+		}
+	}
 
-	if args.Mode == common.TrainMode {
-		c4.Train(args.Width, args.Height, args.WinStreak, args.Cache)
+	if args.Tui && (args.Mode == common.PlayMode || args.Mode == common.BrowseMode) {
+		err := c4.Tui(args.BoardOptions, args.Cache, args.StartWith, c4.TuiConfig{
+			HideA:       args.HideA,
+			HideB:       args.HideB,
+			AutoAttackA: args.AutoAttackA,
+			AutoAttackB: args.AutoAttackB,
+			Scores:      args.Scores,
+			Browse:      args.Mode == common.BrowseMode,
+		}, args.SolverOptions...)
+		if err != nil {
+			log.Crit("Terminal UI failed", log.Ctx{"error": err})
+			os.Exit(1)
+		}
+	} else if args.Mode == common.TrainMode {
+		c4.Train(args.BoardOptions, args.Cache, args.SolverOptions...)
 	} else if args.Mode == common.PlayMode {
-		c4.Play(args.Width, args.Height, args.WinStreak, args.Cache, args.HideA, args.HideB,
-			args.AutoAttackA, args.AutoAttackB, args.Scores, args.StartWith)
+		c4.Play(args.BoardOptions, args.Cache, args.HideA, args.HideB,
+			args.AutoAttackA, args.AutoAttackB, args.Scores, args.StartWith, args.SolverOptions...)
 	} else if args.Mode == common.BrowseMode {
-		c4.Browse(args.Width, args.Height, args.WinStreak, args.Cache, args.StartWith, args.RetrainDepth)
+		err := c4.Browse(args.BoardOptions, args.Cache, args.StartWith, args.RetrainDepth, args.Script,
+			args.SolverOptions...)
+		if err != nil {
+			log.Crit("Browse script failed", log.Ctx{"error": err})
+			os.Exit(1)
+		}
+	} else if args.Mode == common.TournamentMode {
+		c4.Tournament(args.BoardOptions, args.Games, args.Openings, args.Seed,
+			args.EngineA, args.EngineB, args.SolverOptions...)
+	} else if args.Mode == common.CacheMode {
+		err := c4.CacheTool(args.BoardOptions, args.Command, args.SolverOptions...)
+		if err != nil {
+			log.Crit("Cache command failed", log.Ctx{"error": err})
+			os.Exit(1)
+		}
 	}
 }
@@ -0,0 +1,167 @@
+package c4
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/igrek51/log15"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPositionPlay(t *testing.T) {
+	position, err := NewPosition()
+	assert.NoError(t, err)
+	assert.Equal(t, 7, position.Width())
+	assert.Equal(t, 6, position.Height())
+	assert.Equal(t, PlayerA, position.NextPlayer())
+
+	assert.NoError(t, position.PlayMoves("010101"))
+	assert.Equal(t, PlayerB, position.Cell(1, 2))
+	assert.Equal(t, NoPlayer, position.Cell(1, 3))
+	clone := position.Clone()
+
+	assert.NoError(t, position.Play(Move{Column: 0}))
+	assert.True(t, position.IsOver())
+	assert.Equal(t, PlayerA, position.Winner())
+	assert.Equal(t, NoPlayer, position.NextPlayer())
+	assert.Empty(t, position.LegalMoves())
+	assert.ErrorIs(t, position.Play(Move{Column: 2}), ErrGameOver)
+
+	assert.False(t, clone.IsOver())
+	assert.Len(t, clone.Moves(), 6)
+	assert.Len(t, clone.LegalMoves(), 7)
+	assert.Equal(t, "......./......./......./AB...../AB...../AB.....", clone.String())
+}
+
+func TestIllegalMoves(t *testing.T) {
+	position, err := NewPosition(WithSize(4, 2))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, position.Play(Move{Column: 4}), ErrIllegalMove)
+	assert.ErrorIs(t, position.Play(Move{Column: 0, Pop: true}), ErrIllegalMove)
+	assert.ErrorIs(t, position.PlayMoves("00x"), ErrIllegalMove)
+	assert.Empty(t, position.Moves())
+	assert.NoError(t, position.PlayMoves("00"))
+	assert.ErrorIs(t, position.PlayMoves("0"), ErrIllegalMove)
+	assert.Equal(t, []Move{{Column: 0}, {Column: 0}}, position.Moves())
+
+	position, err = NewPosition(WithSize(4, 4), WithVariant(PopOutVariant))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, position.PlayMoves("0p0"), ErrIllegalMove)
+	assert.NoError(t, position.PlayMoves("01p0"))
+	assert.Equal(t, []Move{{Column: 0}, {Column: 1}, {Column: 0, Pop: true}}, position.Moves())
+}
+
+func TestInvalidOptions(t *testing.T) {
+	_, err := NewPosition(WithSize(8, 6))
+	assert.Error(t, err)
+	_, err = NewPosition(WithVariant("gravityless"))
+	assert.Error(t, err)
+	_, err = NewSolver(WithBackend("quantum"))
+	assert.Error(t, err)
+	_, err = NewSolver(WithSize(5, 4), WithBackend(InlineBackend))
+	assert.Error(t, err)
+	_, err = NewSolver(WithCacheLimit(0))
+	assert.Error(t, err)
+}
+
+func TestParseMove(t *testing.T) {
+	move, err := ParseMove("p3")
+	assert.NoError(t, err)
+	assert.Equal(t, Move{Column: 3, Pop: true}, move)
+	assert.Equal(t, "p3", move.String())
+	_, err = ParseMove("x")
+	assert.Error(t, err)
+}
+
+func TestSolve(t *testing.T) {
+	solver, err := NewSolver(WithSize(4, 4), WithWinStreak(3))
+	assert.NoError(t, err)
+	position := solver.NewPosition()
+	assert.NoError(t, position.PlayMoves("1122"))
+
+	result, err := solver.Solve(context.Background(), position)
+	assert.NoError(t, err)
+	assert.Equal(t, PlayerA, result.Player)
+	assert.Equal(t, Win, result.Outcome)
+	assert.Len(t, result.Moves, 4)
+	assert.Contains(t, result.BestMoves, Move{Column: 0})
+	assert.Contains(t, result.BestMoves, Move{Column: 3})
+	assert.Greater(t, solver.Cache().Size(), 0)
+
+	assert.NoError(t, position.PlayMoves("0"))
+	_, err = solver.Solve(context.Background(), position)
+	assert.ErrorIs(t, err, ErrGameOver)
+}
+
+func TestSolveCancelled(t *testing.T) {
+	solver, err := NewSolver(WithSize(4, 4), WithWinStreak(3))
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = solver.Solve(ctx, solver.NewPosition())
+	assert.ErrorIs(t, err, context.Canceled)
+
+	solver, err = NewSolver(WithCacheLimit(1_000_000))
+	assert.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = solver.Solve(ctx, solver.NewPosition())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSharedCache(t *testing.T) {
+	cache, err := NewCache(WithSize(4, 4), WithWinStreak(3), WithCacheLimit(1_000_000))
+	assert.NoError(t, err)
+	first, err := NewSolver(WithSize(4, 4), WithWinStreak(3), WithCache(cache))
+	assert.NoError(t, err)
+	second, err := NewSolver(WithSize(4, 4), WithWinStreak(3), WithCache(cache))
+	assert.NoError(t, err)
+
+	_, err = first.Solve(context.Background(), first.NewPosition())
+	assert.NoError(t, err)
+	size := cache.Size()
+	assert.Greater(t, size, 0)
+	_, err = second.Solve(context.Background(), second.NewPosition())
+	assert.NoError(t, err)
+	assert.Equal(t, size, cache.Size())
+
+	_, err = NewSolver(WithSize(4, 4), WithWinStreak(4), WithCache(cache))
+	assert.ErrorIs(t, err, ErrIncompatibleCache)
+	position, err := NewPosition(WithSize(5, 4))
+	assert.NoError(t, err)
+	_, err = first.Solve(context.Background(), position)
+	assert.ErrorIs(t, err, ErrIncompatiblePosition)
+}
+
+func TestCacheSaveLoad(t *testing.T) {
+	solver, err := NewSolver(WithSize(4, 4), WithWinStreak(3))
+	assert.NoError(t, err)
+	_, err = solver.Solve(context.Background(), solver.NewPosition())
+	assert.NoError(t, err)
+	filename := filepath.Join(t.TempDir(), "cache.protobuf")
+	assert.NoError(t, solver.Cache().Save(filename))
+
+	cache, err := NewCache(WithSize(4, 4), WithWinStreak(3))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cache.Size())
+	assert.NoError(t, cache.Load(filename))
+	assert.Greater(t, cache.Size(), 0)
+	assert.Error(t, cache.Load(filepath.Join(t.TempDir(), "missing.protobuf")))
+}
+
+func TestLogger(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger := log.New()
+	logger.SetHandler(log.StreamHandler(out, log.LogfmtFormat()))
+	solver, err := NewSolver(WithSize(4, 4), WithWinStreak(3), WithLogger(logger))
+	assert.NoError(t, err)
+	assert.NoError(t, solver.Cache().Save(filepath.Join(t.TempDir(), "cache.protobuf")))
+
+	assert.Contains(t, out.String(), "msg=\"Solver configured\"")
+	assert.Contains(t, out.String(), "msg=\"Cache saved\"")
+	_, err = NewSolver(WithLogger(nil))
+	assert.Error(t, err)
+}
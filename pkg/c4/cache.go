@@ -0,0 +1,84 @@
+package c4
+
+import (
+	"sync"
+
+	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
+
+	"github.com/igrek51/connect4solver/solver/backends"
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// Cache keeps endings of solved positions of one game and one solver backend,
+// it can be shared by several solvers of the same game
+type Cache struct {
+	cache    common.ICache
+	identity string
+	logger   log.Logger
+	mu       sync.Mutex
+}
+
+// NewCache creates an empty cache for the game configured with options
+func NewCache(options ...Option) (*Cache, error) {
+	config, err := newConfig(options...)
+	if err != nil {
+		return nil, err
+	}
+	board, err := config.newBoard()
+	if err != nil {
+		return nil, err
+	}
+	backendSolver, backend, err := newBackendSolver(config, board,
+		common.WithCacheLimit(config.cacheLimit), common.WithLogger(config.logger))
+	if err != nil {
+		return nil, err
+	}
+	return newCache(backendSolver.Cache(), board, backend, config.logger), nil
+}
+
+func newCache(cache common.ICache, board *common.Board, backend Backend, logger log.Logger) *Cache {
+	return &Cache{
+		cache:    cache,
+		identity: cacheIdentity(board, backend),
+		logger:   logger,
+	}
+}
+
+// cacheIdentity tells apart caches of different games or incompatible solver implementations
+func cacheIdentity(board *common.Board, backend Backend) string {
+	return gameIdentity(board) + "/" + string(backend)
+}
+
+// newBackendSolver creates solver of the backend chosen in config
+func newBackendSolver(
+	config *config, board *common.Board, options ...common.SolverOption,
+) (common.IMoveSolver, Backend, error) {
+	backend := config.resolveBackend(board)
+	backendSolver, err := backends.NewMoveSolver(board, string(backend), options...)
+	if err != nil {
+		return nil, backend, err
+	}
+	return backendSolver, backend, nil
+}
+
+// Load adds entries from the cache file, saved by the CLI or Save
+func (c *Cache) Load(filename string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return errors.Wrap(common.LoadCacheFile(c.cache, filename, c.logger), "loading cache")
+}
+
+// Save writes entries (up to the half of the max cached depth) to the cache file in protobuf format
+func (c *Cache) Save(filename string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return errors.Wrap(common.SaveCacheFileFormat(c.cache, filename, common.ProtobufCacheFormat, c.logger), "saving cache")
+}
+
+// Size returns number of cached entries
+func (c *Cache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int(c.cache.Size())
+}
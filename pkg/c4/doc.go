@@ -0,0 +1,16 @@
+// Package c4 is an embeddable API of the Connect Four solver.
+//
+// A game is described with options (board size, win streak, rules variant, turns schedule, board shape),
+// the same set of options creates positions, caches and solvers compatible with each other:
+//
+//	solver, err := c4.NewSolver(c4.WithSize(7, 6))
+//	position := solver.NewPosition()
+//	err = position.PlayMoves("3332")
+//	result, err := solver.Solve(ctx, position)
+//
+// Solving stops when the context is done. Solvers and caches don't print anything nor read settings
+// of the CLI (cache directory, file format, colors): the cache limit and the logger are given with options,
+// records are discarded unless WithLogger is used. So solvers of different games can be used side by side.
+// A solver isn't safe for concurrent use, but a Cache may be shared by solvers of the same game,
+// which then take turns using it.
+package c4
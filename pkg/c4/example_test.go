@@ -0,0 +1,79 @@
+package c4_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/igrek51/connect4solver/pkg/c4"
+)
+
+func ExampleSolver_Solve() {
+	solver, err := c4.NewSolver(c4.WithSize(4, 4), c4.WithWinStreak(3))
+	if err != nil {
+		panic(err)
+	}
+	position := solver.NewPosition()
+	if err := position.PlayMoves("12"); err != nil {
+		panic(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	result, err := solver.Solve(ctx, position)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(result.Player, result.Outcome, result.BestMoves)
+	for _, move := range result.Moves {
+		fmt.Println(move.Move, move.Outcome)
+	}
+	// Output:
+	// A win [1 2]
+	// 0 loss
+	// 1 win
+	// 2 win
+	// 3 loss
+}
+
+func ExamplePosition_PlayMoves() {
+	position, err := c4.NewPosition(c4.WithSize(4, 4), c4.WithWinStreak(3))
+	if err != nil {
+		panic(err)
+	}
+	if err := position.PlayMoves("1122"); err != nil {
+		panic(err)
+	}
+	fmt.Println(position.NextPlayer(), position.LegalMoves())
+	if err := position.Play(c4.Move{Column: 3}); err != nil {
+		panic(err)
+	}
+	fmt.Println(position.IsOver(), position.Winner(), position)
+	// Output:
+	// A [0 1 2 3]
+	// true A ..../..../.BB./.AAA
+}
+
+func ExampleWithCache() {
+	cache, err := c4.NewCache(c4.WithSize(4, 4), c4.WithWinStreak(3), c4.WithCacheLimit(1_000_000))
+	if err != nil {
+		panic(err)
+	}
+	for _, moves := range []string{"", "12"} {
+		solver, err := c4.NewSolver(c4.WithSize(4, 4), c4.WithWinStreak(3), c4.WithCache(cache))
+		if err != nil {
+			panic(err)
+		}
+		position := solver.NewPosition()
+		if err := position.PlayMoves(moves); err != nil {
+			panic(err)
+		}
+		result, err := solver.Solve(context.Background(), position)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(result.Player, result.Outcome)
+	}
+	// Output:
+	// A win
+	// A win
+}
@@ -0,0 +1,182 @@
+package c4
+
+import (
+	"fmt"
+
+	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
+
+	"github.com/igrek51/connect4solver/solver/backends"
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// Variant of game rules
+type Variant string
+
+const (
+	// StandardVariant - players drop tokens only
+	StandardVariant = Variant(common.StandardVariant)
+	// PopOutVariant - player may also remove own token from the bottom of a column instead of dropping
+	PopOutVariant = Variant(common.PopOutVariant)
+	// MisereVariant - completing a line loses the game
+	MisereVariant = Variant(common.MisereVariant)
+	// CylinderVariant - horizontal and diagonal lines wrap around from the last column to the first one
+	CylinderVariant = Variant(common.CylinderVariant)
+)
+
+// Backend is an implementation of the solver
+type Backend string
+
+const (
+	// AutoBackend chooses the fastest implementation supporting the game
+	AutoBackend = Backend(backends.Auto)
+	// GenericBackend supports all boards except PopOut variant
+	GenericBackend = Backend(backends.Generic)
+	// InlineBackend is optimized for the standard 7x6 board
+	InlineBackend = Backend(backends.Inline)
+	// PopOutBackend supports PopOut variant only
+	PopOutBackend = Backend(backends.PopOut)
+)
+
+type config struct {
+	boardOptions []common.Option
+	cache        *Cache
+	cacheLimit   int
+	backend      Backend
+	progress     ProgressListener
+	logger       log.Logger
+}
+
+// Option configures the game, cache or solver, options are applied in order
+type Option func(*config) error
+
+func newConfig(options ...Option) (*config, error) {
+	// set defaults
+	c := &config{
+		boardOptions: []common.Option{common.WithSize(7, 6), common.WithWinStreak(4)},
+		cacheLimit:   common.DefaultCacheSizeLimit,
+		backend:      AutoBackend,
+		logger:       common.DiscardLogger(),
+	}
+
+	// apply options
+	for _, opt := range options {
+		if err := opt(c); err != nil {
+			return nil, errors.Wrap(err, "applying option")
+		}
+	}
+	return c, nil
+}
+
+func (c *config) newBoard() (*common.Board, error) {
+	return common.BuildBoard(c.boardOptions...)
+}
+
+func (c *config) resolveBackend(board *common.Board) Backend {
+	if c.backend == AutoBackend {
+		return Backend(backends.Choose(board))
+	}
+	return c.backend
+}
+
+func (c *config) withBoardOption(option common.Option) {
+	c.boardOptions = append(c.boardOptions, option)
+}
+
+// WithSize sets number of columns (1-7) and rows (1-6), default is 7x6
+func WithSize(width int, height int) Option {
+	return func(c *config) error {
+		c.withBoardOption(common.WithSize(width, height))
+		return nil
+	}
+}
+
+// WithWinStreak sets number of tokens in a line needed to win, default is 4
+func WithWinStreak(winStreak int) Option {
+	return func(c *config) error {
+		c.withBoardOption(common.WithWinStreak(winStreak))
+		return nil
+	}
+}
+
+func WithVariant(variant Variant) Option {
+	return func(c *config) error {
+		if _, err := common.ParseVariant(string(variant)); err != nil {
+			return err
+		}
+		c.withBoardOption(common.WithVariant(common.Variant(variant)))
+		return nil
+	}
+}
+
+// WithTurns sets how many moves players make in consecutive turns, the last turn length repeats,
+// eg. WithTurns(1, 2) means A makes one move, then players make two moves each
+func WithTurns(turns ...int) Option {
+	return func(c *config) error {
+		c.withBoardOption(common.WithTurnSchedule(common.TurnSchedule(turns)))
+		return nil
+	}
+}
+
+// WithLayout sets board size and shape from rows of cells (top row first, separated by "/" or new lines):
+// "." - empty cell, "X" - neutral token blocking the cell, "#" - cell outside of the board shape
+func WithLayout(layout string) Option {
+	return func(c *config) error {
+		c.withBoardOption(common.WithLayout(layout))
+		return nil
+	}
+}
+
+// WithCache makes solver use the cache created for the same game instead of a new one
+func WithCache(cache *Cache) Option {
+	return func(c *config) error {
+		if cache == nil {
+			return errors.New("cache is nil")
+		}
+		c.cache = cache
+		return nil
+	}
+}
+
+// WithCacheLimit sets memory limit (number of entries) of created cache
+func WithCacheLimit(entries int) Option {
+	return func(c *config) error {
+		if entries <= 0 {
+			return errors.New("cache limit should be positive")
+		}
+		c.cacheLimit = entries
+		return nil
+	}
+}
+
+// WithBackend chooses solver implementation, AutoBackend by default
+func WithBackend(backend Backend) Option {
+	return func(c *config) error {
+		switch backend {
+		case AutoBackend, GenericBackend, InlineBackend, PopOutBackend:
+			c.backend = backend
+			return nil
+		}
+		return fmt.Errorf("unknown backend: %s", backend)
+	}
+}
+
+// WithProgressListener makes solver report progress of long solves to the listener
+func WithProgressListener(listener ProgressListener) Option {
+	return func(c *config) error {
+		c.progress = listener
+		return nil
+	}
+}
+
+// WithLogger makes solver and cache write debug records (configuration, cache clears, loading and saving files)
+// to the logger, nothing is logged by default
+func WithLogger(logger log.Logger) Option {
+	return func(c *config) error {
+		if logger == nil {
+			return errors.New("logger is nil")
+		}
+		c.logger = logger
+		return nil
+	}
+}
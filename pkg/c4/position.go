@@ -0,0 +1,306 @@
+package c4
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/igrek51/connect4solver/solver/common"
+	"github.com/igrek51/connect4solver/solver/generic_solver"
+)
+
+var (
+	ErrIllegalMove          = errors.New("illegal move")
+	ErrGameOver             = errors.New("game is over")
+	ErrIncompatiblePosition = errors.New("position belongs to a different game")
+	ErrIncompatibleCache    = errors.New("cache belongs to a different game")
+)
+
+// Player owning a token or making a move, zero value means none of the players
+type Player int
+
+const (
+	NoPlayer Player = iota
+	// PlayerA moves first
+	PlayerA
+	PlayerB
+	// Neutral token blocks the cell, not belonging to any player
+	Neutral
+)
+
+func (p Player) String() string {
+	switch p {
+	case PlayerA:
+		return "A"
+	case PlayerB:
+		return "B"
+	case Neutral:
+		return "X"
+	}
+	return "none"
+}
+
+func fromCommonPlayer(player common.Player) Player {
+	switch player {
+	case common.PlayerA:
+		return PlayerA
+	case common.PlayerB:
+		return PlayerB
+	case common.Neutral:
+		return Neutral
+	}
+	return NoPlayer
+}
+
+// Move drops a token to the column (counting from 0) or pops out own token from its bottom in PopOut variant
+type Move struct {
+	Column int
+	Pop    bool
+}
+
+// ParseMove reads move in the CLI notation, eg. "3" or "p3" for popping out
+func ParseMove(input string) (Move, error) {
+	input = strings.TrimSpace(input)
+	pop := strings.HasPrefix(input, "p")
+	column, err := strconv.Atoi(strings.TrimPrefix(input, "p"))
+	if err != nil {
+		return Move{}, errors.Wrap(err, "invalid column number")
+	}
+	return Move{Column: column, Pop: pop}, nil
+}
+
+func (m Move) String() string {
+	if m.Pop {
+		return fmt.Sprintf("p%d", m.Column)
+	}
+	return strconv.Itoa(m.Column)
+}
+
+// Position is a state of the game along with the moves leading to it
+type Position struct {
+	board    *common.Board
+	referee  common.LineChecker
+	rules    *common.Rules
+	identity string
+	moves    []Move
+	history  positionHistory
+	winner   Player
+	over     bool
+}
+
+// NewPosition creates a start position of the game configured with options
+func NewPosition(options ...Option) (*Position, error) {
+	config, err := newConfig(options...)
+	if err != nil {
+		return nil, err
+	}
+	board, err := config.newBoard()
+	if err != nil {
+		return nil, err
+	}
+	return newPosition(board), nil
+}
+
+func newPosition(board *common.Board) *Position {
+	p := &Position{
+		board:    board,
+		referee:  newLineChecker(board),
+		rules:    common.NewRules(board.Variant),
+		identity: gameIdentity(board),
+		history:  positionHistory{},
+	}
+	p.over = p.isATie()
+	return p
+}
+
+func newLineChecker(board *common.Board) common.LineChecker {
+	if board.Variant == common.PopOutVariant {
+		return popOutChecker{generic_solver.NewBitboardReferee(board)}
+	}
+	return generic_solver.NewReferee(board)
+}
+
+// popOutChecker checks the whole board after popping out, as shifted tokens may complete lines anywhere in the column
+type popOutChecker struct {
+	*generic_solver.BitboardReferee
+}
+
+func (c popOutChecker) HasPlayerWon(board *common.Board, move int, y int, player common.Player) bool {
+	if board.IsPopMove(move) {
+		return c.HasPlayerLine(board, player)
+	}
+	return c.BitboardReferee.HasPlayerWon(board, move, y, player)
+}
+
+// gameIdentity tells apart boards of different games
+func gameIdentity(board *common.Board) string {
+	return fmt.Sprintf("%dx%d/%d/%s/%s/%s",
+		board.W, board.H, board.WinStreak, board.Variant, board.Turns, board.ShapeHash())
+}
+
+// Play makes a move of the next player
+func (p *Position) Play(move Move) error {
+	if p.over {
+		return ErrGameOver
+	}
+	slot, err := p.moveSlot(move)
+	if err != nil {
+		return err
+	}
+	player := p.board.NextPlayer()
+	if !p.board.CanPlay(slot, player) {
+		return errors.Wrapf(ErrIllegalMove, "can't play %s", move)
+	}
+
+	y := p.board.MakeMove(slot, player)
+	p.moves = append(p.moves, move)
+	if winner := p.rules.MoveWinner(p.referee, p.board, slot, y, player); winner != common.Empty {
+		p.winner = fromCommonPlayer(winner)
+		p.over = true
+	} else if p.isATie() {
+		p.over = true
+	} else if p.history.record(p.board) >= common.MaxRepetitions {
+		p.over = true
+	}
+	return nil
+}
+
+// PlayMoves makes consecutive moves in the CLI notation, eg. "3332" or "0p0" in PopOut variant.
+// Position isn't changed if any of the moves fails.
+func (p *Position) PlayMoves(moves string) error {
+	next := p.Clone()
+	for idx := 0; idx < len(moves); idx++ {
+		notation := moves[idx : idx+1]
+		if moves[idx] == 'p' && idx+1 < len(moves) {
+			idx++
+			notation = moves[idx-1 : idx+1]
+		}
+		move, err := ParseMove(notation)
+		if err != nil {
+			return errors.Wrapf(ErrIllegalMove, "move %d: %s", len(next.moves)+1, err)
+		}
+		if err := next.Play(move); err != nil {
+			return errors.Wrapf(err, "move %d", len(next.moves)+1)
+		}
+	}
+	*p = *next
+	return nil
+}
+
+func (p *Position) moveSlot(move Move) (int, error) {
+	if move.Column < 0 || move.Column >= p.board.W {
+		return 0, errors.Wrapf(ErrIllegalMove, "column %d is out of range", move.Column)
+	}
+	if move.Pop {
+		if p.board.Variant != common.PopOutVariant {
+			return 0, errors.Wrap(ErrIllegalMove, "popping out is allowed only in PopOut variant")
+		}
+		return p.board.PopMove(move.Column), nil
+	}
+	return move.Column, nil
+}
+
+func (p *Position) slotMove(slot int) Move {
+	if p.board.IsPopMove(slot) {
+		return Move{Column: slot - p.board.W, Pop: true}
+	}
+	return Move{Column: slot}
+}
+
+type positionKey struct {
+	state      common.BoardKey
+	nextPlayer common.Player
+}
+
+// positionHistory counts occurrences of positions, as they may repeat when popping out tokens
+type positionHistory map[positionKey]int
+
+func (h positionHistory) record(board *common.Board) int {
+	key := positionKey{state: board.State, nextPlayer: board.NextPlayer()}
+	h[key]++
+	return h[key]
+}
+
+// isATie checks if next player has no possible moves
+func (p *Position) isATie() bool {
+	player := p.board.NextPlayer()
+	for slot := 0; slot < p.board.MoveSlots(); slot++ {
+		if p.board.CanPlay(slot, player) {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone makes an independent copy of the position
+func (p *Position) Clone() *Position {
+	clone := *p
+	clone.board = p.board.Clone()
+	clone.moves = append([]Move{}, p.moves...)
+	clone.history = positionHistory{}
+	for key, count := range p.history {
+		clone.history[key] = count
+	}
+	return &clone
+}
+
+// NextPlayer returns the player to move, or NoPlayer when the game is over
+func (p *Position) NextPlayer() Player {
+	if p.over {
+		return NoPlayer
+	}
+	return fromCommonPlayer(p.board.NextPlayer())
+}
+
+// LegalMoves lists moves available to the next player
+func (p *Position) LegalMoves() []Move {
+	moves := []Move{}
+	if p.over {
+		return moves
+	}
+	player := p.board.NextPlayer()
+	for slot := 0; slot < p.board.MoveSlots(); slot++ {
+		if p.board.CanPlay(slot, player) {
+			moves = append(moves, p.slotMove(slot))
+		}
+	}
+	return moves
+}
+
+// Moves lists moves played so far
+func (p *Position) Moves() []Move {
+	return append([]Move{}, p.moves...)
+}
+
+// IsOver tells if the game has ended with a win or a tie
+func (p *Position) IsOver() bool {
+	return p.over
+}
+
+// Winner returns the winner of the finished game, NoPlayer in case of a tie or unfinished game
+func (p *Position) Winner() Player {
+	return p.winner
+}
+
+func (p *Position) Width() int {
+	return p.board.W
+}
+
+func (p *Position) Height() int {
+	return p.board.H
+}
+
+// Cell returns token at given cell, counting rows from the bottom
+func (p *Position) Cell(column int, row int) Player {
+	if column < 0 || column >= p.board.W || row < 0 || row >= p.board.Heights[column] {
+		return NoPlayer
+	}
+	return fromCommonPlayer(p.board.GetCell(column, row))
+}
+
+// String renders rows of cells (top row first) separated by "/", eg. "......./.../...A..."
+func (p *Position) String() string {
+	return p.board.LayoutString()
+}
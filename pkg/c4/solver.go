@@ -0,0 +1,195 @@
+package c4
+
+import (
+	"context"
+	"time"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// Outcome of the game for a player, assuming perfect play of both sides
+type Outcome int
+
+const (
+	Unknown Outcome = iota
+	Loss
+	Tie
+	Win
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Loss:
+		return "loss"
+	case Tie:
+		return "tie"
+	case Win:
+		return "win"
+	}
+	return "unknown"
+}
+
+func endingOutcome(ending common.Player, player common.Player) Outcome {
+	switch ending {
+	case common.NoMove:
+		return Unknown
+	case common.Empty:
+		return Tie
+	case player:
+		return Win
+	}
+	return Loss
+}
+
+// MoveOutcome is an outcome of making the move
+type MoveOutcome struct {
+	Move    Move
+	Outcome Outcome
+}
+
+// Result of solving a position for the player to move
+type Result struct {
+	Player Player
+	// Outcome of the best move
+	Outcome Outcome
+	// Moves lists outcomes of all legal moves
+	Moves []MoveOutcome
+	// BestMoves lists moves leading to the best outcome
+	BestMoves []Move
+}
+
+// Progress is a snapshot of the solving state, reported periodically during long solves
+type Progress struct {
+	// Fraction of the solved search space, from 0 to 1
+	Fraction float64
+	// ETA is an estimated time left, zero if unknown
+	ETA              time.Duration
+	Iterations       uint64
+	IterationsPerSec uint64
+	CacheSize        uint64
+	// Board is the currently considered board in the notation of Position.String
+	Board string
+}
+
+// ProgressListener receives progress of solving, it's called from the goroutine calling Solve
+type ProgressListener interface {
+	OnProgress(progress Progress)
+}
+
+// ProgressFunc adapts a function to ProgressListener
+type ProgressFunc func(progress Progress)
+
+func (f ProgressFunc) OnProgress(progress Progress) {
+	f(progress)
+}
+
+func progressAdapter(listener ProgressListener) common.ProgressListener {
+	return common.ProgressFunc(func(progress common.Progress) {
+		listener.OnProgress(Progress{
+			Fraction:         progress.Fraction,
+			ETA:              progress.ETA,
+			Iterations:       progress.Iterations,
+			IterationsPerSec: progress.IterationsPerSec,
+			CacheSize:        progress.Cache.Size,
+			Board:            progress.Board.LayoutString(),
+		})
+	})
+}
+
+// Solver finds outcomes of moves in positions of one game
+type Solver struct {
+	solver   common.IMoveSolver
+	board    *common.Board
+	cache    *Cache
+	identity string
+}
+
+// NewSolver creates solver of the game configured with options, with a new cache unless WithCache is given
+func NewSolver(options ...Option) (*Solver, error) {
+	config, err := newConfig(options...)
+	if err != nil {
+		return nil, err
+	}
+	board, err := config.newBoard()
+	if err != nil {
+		return nil, err
+	}
+
+	solverOptions := []common.SolverOption{common.WithCacheLimit(config.cacheLimit), common.WithLogger(config.logger)}
+	if config.progress != nil {
+		solverOptions = append(solverOptions, common.WithProgressListener(progressAdapter(config.progress)))
+	}
+	if config.cache != nil {
+		if config.cache.identity != cacheIdentity(board, config.resolveBackend(board)) {
+			return nil, ErrIncompatibleCache
+		}
+		solverOptions = append(solverOptions, common.WithCache(config.cache.cache))
+	}
+	backendSolver, backend, err := newBackendSolver(config, board, solverOptions...)
+	if err != nil {
+		return nil, err
+	}
+	cache := config.cache
+	if cache == nil {
+		cache = newCache(backendSolver.Cache(), board, backend, config.logger)
+	}
+	return &Solver{
+		solver:   backendSolver,
+		board:    board,
+		cache:    cache,
+		identity: gameIdentity(board),
+	}, nil
+}
+
+// NewPosition creates a start position of the solver's game
+func (s *Solver) NewPosition() *Position {
+	return newPosition(s.board.Clone())
+}
+
+// Cache returns the cache used by the solver
+func (s *Solver) Cache() *Cache {
+	return s.cache
+}
+
+// Solve finds outcomes of the legal moves in the position, it returns ctx.Err() when the context is done before
+func (s *Solver) Solve(ctx context.Context, position *Position) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	if position.identity != s.identity {
+		return Result{}, ErrIncompatiblePosition
+	}
+	if position.over {
+		return Result{}, ErrGameOver
+	}
+	s.cache.mu.Lock()
+	defer s.cache.mu.Unlock()
+
+	endings, err := s.solver.MovesEndingsContext(ctx, position.board.Clone())
+	if err != nil {
+		return Result{}, err
+	}
+
+	player := position.board.NextPlayer()
+	result := Result{
+		Player:    fromCommonPlayer(player),
+		Moves:     []MoveOutcome{},
+		BestMoves: []Move{},
+	}
+	for slot, ending := range endings {
+		outcome := endingOutcome(ending, player)
+		if outcome == Unknown {
+			continue
+		}
+		move := position.slotMove(slot)
+		result.Moves = append(result.Moves, MoveOutcome{Move: move, Outcome: outcome})
+		if outcome > result.Outcome {
+			result.Outcome = outcome
+			result.BestMoves = []Move{}
+		}
+		if outcome == result.Outcome {
+			result.BestMoves = append(result.BestMoves, move)
+		}
+	}
+	return result, nil
+}
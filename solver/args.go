@@ -3,6 +3,9 @@ package solver
 import (
 	"flag"
 	"fmt"
+	"os"
+
+	log "github.com/igrek51/log15"
 
 	"github.com/igrek51/connect4solver/solver/common"
 )
@@ -15,6 +18,8 @@ type CliArgs struct {
 	Mode         common.Mode
 	StartWith    string
 	RetrainDepth int
+	// Script is a file with browse commands, "-" for stdin
+	Script string
 
 	Profile     bool
 	Cache       bool
@@ -23,6 +28,22 @@ type CliArgs struct {
 	AutoAttackA bool
 	AutoAttackB bool
 	Scores      bool
+	Tui         bool
+
+	Games    int
+	Openings int
+	Seed     int64
+	EngineA  string
+	EngineB  string
+
+	// Command is a subcommand with its arguments, eg. cache stats
+	Command []string
+
+	MetricsAddr string
+	Metrics     *MetricsCollector
+
+	BoardOptions  []common.Option
+	SolverOptions []common.SolverOption
 }
 
 func GetArgs() *CliArgs {
@@ -32,6 +53,10 @@ func GetArgs() *CliArgs {
 	flag.IntVar(&args.Height, "height", 6, "board height")
 	flag.IntVar(&args.WinStreak, "win", 4, "win streak")
 	boardSize := flag.String("size", "", "board size (eg. 7x6)")
+	variant := flag.String("variant", string(common.StandardVariant), "Game rules variant: standard, popout, misere, cylinder")
+	turns := flag.String("turns", "", "Number of moves in consecutive turns, the last one repeats (eg. 1,2)")
+	layout := flag.String("layout", "", "Board shape, rows from the top separated by /, "+
+		"'.' - empty cell, 'X' - neutral token, '#' - no cell (eg. #...#/...../X...X)")
 
 	flag.BoolVar(&args.Profile, "profile", false, "Enable pprof CPU profiling")
 	nocache := flag.Bool("nocache", false, "Load cached endings from file")
@@ -44,14 +69,58 @@ func GetArgs() *CliArgs {
 	train := flag.Bool("train", false, "Training mode")
 	play := flag.Bool("play", false, "Playing mode")
 	browse := flag.Bool("browse", false, "Browsing mode for debugging purposes")
+	tournament := flag.Bool("tournament", false, "Self-play tournament between two engines")
+	flag.BoolVar(&args.Tui, "tui", false, "Full-screen terminal UI for playing and browsing")
 
 	flag.StringVar(&args.StartWith, "startwith", "", "Positions of first consecutive moves to start with (eg. 0016)")
 	flag.IntVar(&args.RetrainDepth, "retrain", -1, "Retrain worst scenarios until given depth")
+	flag.StringVar(&args.Script, "script", "", "Run browse commands from given file (- for stdin), exiting with non-zero status on the first error")
+
+	flag.IntVar(&args.Games, "games", 10, "Number of tournament games")
+	flag.IntVar(&args.Openings, "openings", 0, "Number of random opening moves in tournament games")
+	flag.Int64Var(&args.Seed, "seed", 0, "Random seed for tournament (0 - random)")
+	flag.StringVar(&args.EngineA, "engine-a", "", "First tournament engine (eg. level=100,backend=generic,cache=false,scores=true)")
+	flag.StringVar(&args.EngineB, "engine-b", "", "Second tournament engine (eg. level=50)")
+
+	forcedMoves := flag.Bool("forced-moves", true, "Detect forced blocks and double threats before searching deeper")
+	referee := flag.String("referee", string(common.LookupRefereeKind), "Winning condition checker: lookup, bitboard")
+	moveOrder := flag.String("move-order", string(common.StaticMoveOrder), "Move ordering policy: static, threat")
 
 	cacheLimit := flag.Int("cache-limit", 0, "Cache memory limit (number of entries)")
+	cacheDir := flag.String("cache-dir", "", "Directory of cache files (default: $"+CacheDirEnv+
+		", ./cache if exists or user cache directory)")
+	cacheFile := flag.String("cache-file", "", "Path of the cache file, overriding directory and name derived from the board")
+	cacheReadOnly := flag.Bool("cache-readonly", false, "Never overwrite the cache file, so it can be shared")
+	cacheFormat := flag.String("cache-format", string(common.ProtobufCacheFormat),
+		"Format of saved cache files: protobuf, compact, compact-gzip (any format is loaded)")
+
+	progress := flag.String("progress", string(BarProgress), "Progress reporting: bar, json (JSON lines on stderr), none")
+	quiet := flag.Bool("quiet", false, "Don't report progress of solving (same as --progress=none)")
+	color := flag.String("color", string(common.ColorAuto), "Colored output: auto (on a terminal unless $"+common.NoColorEnv+" is set), always, never")
+	theme := flag.String("theme", string(common.AsciiTheme), "Symbols of players on the board: ascii, unicode")
+	logLevel := flag.String("log-level", "debug", "Minimal level of logs: debug, info, warn, error, crit")
+	logFormat := flag.String("log-format", string(TextLogFormat), "Format of logs: text, json")
+	logFile := flag.String("log-file", "", "Write logs to given file instead of stdout")
+	flag.StringVar(&args.MetricsAddr, "metrics-addr", "", "Serve Prometheus metrics of solving at /metrics on given address (eg. :9090)")
 
 	flag.Parse()
 
+	colorMode, err := common.ParseColorMode(*color)
+	if err != nil {
+		log.Crit("Invalid argument", log.Ctx{"error": err})
+		os.Exit(2)
+	}
+	themeValue, err := common.ParseTheme(*theme)
+	if err != nil {
+		log.Crit("Invalid argument", log.Ctx{"error": err})
+		os.Exit(2)
+	}
+	common.Display = common.NewRenderer(themeValue, colorMode)
+	if err := SetupLogging(*logLevel, LogFormat(*logFormat), *logFile); err != nil {
+		log.Crit("Invalid argument", log.Ctx{"error": err})
+		os.Exit(2)
+	}
+
 	if boardSize != nil && *boardSize != "" {
 		fmt.Sscanf(*boardSize, "%dx%d", &args.Width, &args.Height)
 	}
@@ -68,10 +137,81 @@ func GetArgs() *CliArgs {
 	if *browse {
 		args.Mode = common.BrowseMode
 	}
+	if *tournament {
+		args.Mode = common.TournamentMode
+	}
+	if flag.Arg(0) == string(common.CacheMode) {
+		args.Mode = common.CacheMode
+		args.Command = flag.Args()[1:]
+	}
+
+	variantValue, err := common.ParseVariant(*variant)
+	if err != nil {
+		log.Crit("Invalid argument", log.Ctx{"error": err})
+		os.Exit(2)
+	}
+	turnSchedule, err := common.ParseTurnSchedule(*turns)
+	if err != nil {
+		log.Crit("Invalid argument", log.Ctx{"error": err})
+		os.Exit(2)
+	}
+	args.BoardOptions = append(args.BoardOptions,
+		common.WithSize(args.Width, args.Height),
+		common.WithWinStreak(args.WinStreak),
+		common.WithVariant(variantValue),
+		common.WithTurnSchedule(turnSchedule),
+	)
+	if *layout != "" {
+		args.BoardOptions = append(args.BoardOptions, common.WithLayout(*layout))
+	}
+	moveOrderPolicy, err := common.ParseMoveOrderPolicy(*moveOrder)
+	if err != nil {
+		log.Crit("Invalid argument", log.Ctx{"error": err})
+		os.Exit(2)
+	}
+	refereeKind, err := common.ParseRefereeKind(*referee)
+	if err != nil {
+		log.Crit("Invalid argument", log.Ctx{"error": err})
+		os.Exit(2)
+	}
+	args.SolverOptions = append(args.SolverOptions,
+		common.WithMoveOrder(moveOrderPolicy),
+		common.WithForcedMoves(*forcedMoves),
+		common.WithReferee(refereeKind),
+		common.WithLogger(log.Root()),
+	)
+	if *quiet || (args.Tui && ProgressMode(*progress) == BarProgress) {
+		*progress = string(NoProgress)
+	}
+	progressListener, err := NewProgressListener(ProgressMode(*progress))
+	if err != nil {
+		log.Crit("Invalid argument", log.Ctx{"error": err})
+		os.Exit(2)
+	}
+	progressListeners := common.ProgressListeners{}
+	if progressListener != nil {
+		progressListeners = append(progressListeners, progressListener)
+	}
+	if args.MetricsAddr != "" {
+		args.Metrics = NewMetricsCollector()
+		progressListeners = append(progressListeners, args.Metrics)
+	}
+	if len(progressListeners) > 0 {
+		args.SolverOptions = append(args.SolverOptions, common.WithProgressListener(progressListeners))
+	}
 
 	if *cacheLimit > 0 {
-		common.CacheSizeLimit = *cacheLimit
+		args.SolverOptions = append(args.SolverOptions, common.WithCacheLimit(*cacheLimit))
+	}
+	cacheFileFormat, err := common.ParseCacheFormat(*cacheFormat)
+	if err != nil {
+		log.Crit("Invalid argument", log.Ctx{"error": err})
+		os.Exit(2)
 	}
+	CacheFileFormat = cacheFileFormat
+	CacheDir = ResolveCacheDir(*cacheDir)
+	CacheFile = *cacheFile
+	CacheReadOnly = *cacheReadOnly
 
 	return args
 }
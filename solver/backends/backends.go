@@ -0,0 +1,67 @@
+// Package backends creates solver implementations suited for the board, without the CLI dependencies
+package backends
+
+import (
+	"fmt"
+
+	"github.com/igrek51/connect4solver/solver/common"
+	"github.com/igrek51/connect4solver/solver/generic_solver"
+	"github.com/igrek51/connect4solver/solver/inline7x6"
+	"github.com/igrek51/connect4solver/solver/popout_solver"
+)
+
+const (
+	Auto    = "auto"
+	Generic = "generic"
+	Inline  = "inline"
+	PopOut  = "popout"
+)
+
+// Choose picks the solver implementation suited best for the board and options
+func Choose(board *common.Board, options ...common.SolverOption) string {
+	if board.Variant == common.PopOutVariant {
+		return PopOut
+	}
+	// take precedence with inlined optimized solvers
+	if board.W == 7 && board.H == 6 && board.IsRectangular() && board.Variant != common.CylinderVariant &&
+		common.NewSolverConfig(options...).IsDefault() {
+		return Inline
+	}
+	return Generic
+}
+
+// NewMoveSolver creates solver of explicitly chosen implementation, Auto picks one with Choose
+func NewMoveSolver(
+	board *common.Board, backend string, options ...common.SolverOption,
+) (common.IMoveSolver, error) {
+	if backend == Auto || backend == "" {
+		backend = Choose(board, options...)
+	}
+	if board.Variant == common.PopOutVariant && backend != PopOut {
+		return nil, fmt.Errorf("%s solver doesn't support %s variant", backend, board.Variant)
+	}
+	switch backend {
+	case PopOut:
+		if board.Variant != common.PopOutVariant {
+			return nil, fmt.Errorf("popout solver supports only %s variant", common.PopOutVariant)
+		}
+		return popout_solver.NewMoveSolver(board, options...), nil
+	case Generic:
+		return generic_solver.NewMoveSolver(board, options...), nil
+	case Inline:
+		if board.W != 7 || board.H != 6 {
+			return nil, fmt.Errorf("inline solver supports only 7x6 board, got %dx%d", board.W, board.H)
+		}
+		if !common.NewSolverConfig(options...).IsDefault() {
+			return nil, fmt.Errorf("inline solver supports only default solver options")
+		}
+		if board.Variant == common.CylinderVariant {
+			return nil, fmt.Errorf("inline solver doesn't support %s variant", board.Variant)
+		}
+		if !board.IsRectangular() {
+			return nil, fmt.Errorf("inline solver supports only rectangular boards without neutral tokens")
+		}
+		return inline7x6.NewMoveSolver(board, options...), nil
+	}
+	return nil, fmt.Errorf("unknown solver backend: %s", backend)
+}
@@ -2,206 +2,372 @@ package solver
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
 
 	"github.com/igrek51/connect4solver/solver/common"
 )
 
+// Browse explores positions and their cached endings, running commands typed by the user
+// or from the script file ("-" for stdin). Script stops at the first failing command, returning its error.
 func Browse(
-	width, height, winStreak int,
+	boardOptions []common.Option,
 	cacheEnabled bool,
 	startWithMoves string,
 	retrainDepth int,
-) {
-	board := common.NewBoard(common.WithSize(width, height), common.WithWinStreak(winStreak))
+	script string,
+	solverOptions ...common.SolverOption,
+) error {
+	board := common.NewBoard(boardOptions...)
 	board.ApplyMoves(startWithMoves)
 
-	solver := CreateSolver(board)
-	if cacheEnabled && common.CacheFileExists(board) {
-		common.MustLoadCache(solver.Cache(), board.W, board.H)
+	solver := CreateSolver(board, solverOptions...)
+	if cacheEnabled && CacheFileExists(board) {
+		MustLoadCache(solver.Cache(), board)
 	}
 
 	if retrainDepth > 0 {
-		retrainSolverDepth(board, solver, uint(retrainDepth))
-		common.MustSaveCache(solver.Cache(), board.W, board.H)
-		return
+		if err := retrainSolverDepth(board, solver, uint(retrainDepth)); err != nil {
+			return err
+		}
+		return errors.Wrap(SaveCache(solver.Cache(), board), "saving cache")
 	}
 
-	for {
-		fmt.Println(board.String())
-		player := board.NextPlayer()
-		depth := board.CountMoves()
-		fmt.Printf("Current player: %v, moves: %d\n", player, depth)
+	session := newBrowseSession(board, solver)
+	if script == "" {
+		session.interact(os.Stdin)
+		return nil
+	}
+	in := io.Reader(os.Stdin)
+	if script != "-" {
+		file, err := os.Open(script)
+		if err != nil {
+			return errors.Wrap(err, "opening script")
+		}
+		defer file.Close()
+		in = file
+	}
+	statements, err := parseBrowseScript(in)
+	if err != nil {
+		return err
+	}
+	return session.run(statements, map[string]string{})
+}
 
-		action, x := readNextAction()
-		if action == "quit" {
-			return
-		} else if action == "move" {
-			if x < 0 || x >= board.W {
-				log.Error("Move number is out of range")
-				continue
-			}
-			if !board.CanMakeMove(x) {
-				log.Error("Column is already full")
-				continue
-			}
-			board.Throw(x, player)
-		} else if action == "revert" {
-			if x < 0 || x >= board.W {
-				log.Error("Move number is out of range")
-				continue
-			}
-			if board.StackSize(x) == 0 {
-				log.Error("Column is already empty")
-				continue
-			}
-			board.Revert(x, board.StackSize(x)-1)
-		} else if action == "new" {
-			board.Clear()
-		} else if action == "clear_cache" {
-			solver.Cache().ClearCache(uint(x))
-		} else if action == "clear_cache_from" {
-			for d := uint(x); d < uint(board.W*board.H); d++ {
-				solver.Cache().ClearCache(d)
-			}
-		} else if action == "endings" {
-			startTime := time.Now()
-			endings := solver.MovesEndings(board)
-			if endings != nil {
-				totalElapsed := time.Since(startTime)
-				logger := log.New(log.Ctx{
-					"solveTime": totalElapsed,
-					"endings":   endings,
-				})
-				logger.Info("Board solved", solver.SummaryVars())
-				printEndingsLine(endings, player)
-			}
-		} else if action == "cache" {
-			showCacheStatistics(solver.Cache(), board.W, board.H)
-			depth := board.CountMoves()
-			cachedEndings := getCachedEndings(board, solver)
-			log.Debug("cache statistics", log.Ctx{
-				"depth":          depth,
-				"depthCacheSize": solver.Cache().DepthSize(depth),
-				"cachedEndings":  cachedEndings,
-			})
-			printGameEndingsLine(cachedEndings)
-		} else if action == "save" {
-			common.MustSaveCache(solver.Cache(), board.W, board.H)
-		} else if action == "retrain" {
-			retrainSolverDepth(board, solver, uint(x))
-		}
-	}
-}
-
-func readNextAction() (string, int) {
-	for {
-		fmt.Printf("Enter command (h for help) > ")
-		var command string
-		var x int
+// browseSession is the browsed position with the way to it, changed by consecutive commands
+type browseSession struct {
+	board  *common.Board
+	solver common.IMoveSolver
+	// moves are kept in the game, so that going back to the parent position is possible
+	root      *common.Board
+	game      *common.Game
+	bookmarks []bookmark
+	quit      bool
+}
 
-		in := bufio.NewReader(os.Stdin)
-		command, err := in.ReadString('\n')
+func newBrowseSession(board *common.Board, solver common.IMoveSolver) *browseSession {
+	return &browseSession{
+		board:  board,
+		solver: solver,
+		root:   board.Clone(),
+		game:   common.NewGame(board),
+	}
+}
+
+// interact runs commands typed by the user, failing commands are only reported
+func (s *browseSession) interact(input io.Reader) {
+	in := bufio.NewReader(input)
+	for !s.quit {
+		s.printBoard()
+		fmt.Printf("Enter command (h for help) > ")
+		line, err := in.ReadString('\n')
+		if err == io.EOF && line == "" {
+			fmt.Println()
+			return
+		}
+		if err != nil && err != io.EOF {
+			log.Error("Command read error", log.Ctx{"error": err})
+			return
+		}
+		command, err := parseBrowseCommand(strings.TrimSpace(line))
 		if err != nil {
-			log.Error("Command read error", log.Ctx{"command": command})
+			log.Error("Invalid command", log.Ctx{"error": err})
 			continue
 		}
-		command = strings.TrimSuffix(command, "\n")
-
-		if command == "h" || command == "help" {
-			fmt.Println("Available commands:")
-			fmt.Println("  X, mX - move next player at column X [0-6], eg. m0")
-			fmt.Println("  rX - revert token at column X, eg. r0")
-			fmt.Println("  e - evaluate endings")
-			fmt.Println("  c - show cache statistics & cached endings for current board")
-			fmt.Println("  new - start new game")
-			fmt.Println("  clear X - clear cache at given depth")
-			fmt.Println("  clear X+ - clear cache from given depth")
-			fmt.Println("  retrain X - retrain worst scenarios until given depth")
-			fmt.Println("  save - save cache file")
-			fmt.Println("  q - quit")
-		} else if command == "" {
-			return "", 0
-		} else if command == "q" {
-			return "quit", 0
-		} else if command == "e" {
-			return "endings", 0
-		} else if command == "c" {
-			return "cache", 0
-		} else if command == "new" {
-			return "new", 0
-		} else if command == "save" {
-			return "save", 0
-		} else if strings.HasPrefix(command, "clear") {
-			if command == "clear" {
-				return "clear_cache_from", 0
-			}
-			if strings.HasSuffix(command, "+") {
-				_, err := fmt.Sscanf(command, "clear %d+", &x)
-				if err != nil {
-					log.Error("Invalid number", log.Ctx{"error": err})
-					continue
-				}
-				return "clear_cache_from", x
-			} else {
-				_, err := fmt.Sscanf(command, "clear %d", &x)
-				if err != nil {
-					log.Error("Invalid number", log.Ctx{"error": err})
-					continue
-				}
-				return "clear_cache", x
-			}
-		} else if strings.HasPrefix(command, "retrain") {
-			_, err := fmt.Sscanf(command, "retrain %d", &x)
-			if err != nil {
-				log.Error("Invalid number", log.Ctx{"error": err})
-				continue
+		if err := s.execute(command); err != nil {
+			log.Error("Command failed", log.Ctx{"error": err})
+		}
+	}
+}
+
+func (s *browseSession) printBoard() {
+	fmt.Println(s.board.String())
+	fmt.Printf("Current player: %s, moves: %d\n", common.Display.Player(s.board.NextPlayer()), s.board.CountMoves())
+}
+
+// browseCommand is a parsed command of browsing mode
+type browseCommand struct {
+	action string
+	x      int
+	// moves to play in CLI notation, eg. 0016
+	moves string
+}
+
+// parseBrowseCommand reads the command typed by the user or written in the script
+func parseBrowseCommand(command string) (browseCommand, error) {
+	var x int
+	if command == "h" || command == "help" {
+		return browseCommand{action: "help"}, nil
+	} else if command == "" {
+		return browseCommand{}, nil
+	} else if command == "q" || command == "quit" {
+		return browseCommand{action: "quit"}, nil
+	} else if command == "e" {
+		return browseCommand{action: "endings"}, nil
+	} else if command == "c" {
+		return browseCommand{action: "cache"}, nil
+	} else if command == "new" {
+		return browseCommand{action: "new"}, nil
+	} else if command == "board" {
+		return browseCommand{action: "board"}, nil
+	} else if command == "t" || command == "tree" {
+		return browseCommand{action: "tree"}, nil
+	} else if command == "l" || command == "line" {
+		return browseCommand{action: "line"}, nil
+	} else if command == "b" || command == "back" {
+		return browseCommand{action: "back"}, nil
+	} else if command == "mark" || command == "marks" {
+		return browseCommand{action: command}, nil
+	} else if strings.HasPrefix(command, "go ") {
+		if _, err := fmt.Sscanf(command, "go %d", &x); err != nil {
+			return browseCommand{}, errors.Wrap(err, "invalid number")
+		}
+		return browseCommand{action: "goto", x: x}, nil
+	} else if strings.HasPrefix(command, "play ") {
+		moves := strings.TrimSpace(strings.TrimPrefix(command, "play "))
+		return browseCommand{action: "play", moves: moves}, nil
+	} else if command == "save" {
+		return browseCommand{action: "save"}, nil
+	} else if strings.HasPrefix(command, "clear") {
+		if command == "clear" {
+			return browseCommand{action: "clear_cache_from"}, nil
+		}
+		if strings.HasSuffix(command, "+") {
+			if _, err := fmt.Sscanf(command, "clear %d+", &x); err != nil {
+				return browseCommand{}, errors.Wrap(err, "invalid number")
 			}
-			return "retrain", x
-		} else if strings.HasPrefix(command, "m") {
-			_, err := fmt.Sscanf(command, "m%d", &x)
-			if err != nil {
-				_, err2 := fmt.Sscanf(command, "m %d", &x)
-				if err2 == nil {
-					return "move", x
-				}
-				log.Error("Invalid number", log.Ctx{"error": err})
-				continue
+			return browseCommand{action: "clear_cache_from", x: x}, nil
+		}
+		if _, err := fmt.Sscanf(command, "clear %d", &x); err != nil {
+			return browseCommand{}, errors.Wrap(err, "invalid number")
+		}
+		return browseCommand{action: "clear_cache", x: x}, nil
+	} else if strings.HasPrefix(command, "retrain") {
+		if _, err := fmt.Sscanf(command, "retrain %d", &x); err != nil {
+			return browseCommand{}, errors.Wrap(err, "invalid number")
+		}
+		return browseCommand{action: "retrain", x: x}, nil
+	} else if strings.HasPrefix(command, "m") {
+		if _, err := fmt.Sscanf(command, "m%d", &x); err != nil {
+			if _, err2 := fmt.Sscanf(command, "m %d", &x); err2 != nil {
+				return browseCommand{}, errors.Wrap(err, "invalid number")
 			}
-			return "move", x
-		} else if strings.HasPrefix(command, "r") {
-			_, err := fmt.Sscanf(command, "r%d", &x)
-			if err != nil {
-				log.Error("Invalid number", log.Ctx{"error": err})
-				continue
+		}
+		return browseCommand{action: "move", x: x}, nil
+	} else if strings.HasPrefix(command, "p") {
+		if _, err := fmt.Sscanf(command, "p%d", &x); err != nil {
+			return browseCommand{}, errors.Wrap(err, "invalid number")
+		}
+		return browseCommand{action: "pop", x: x}, nil
+	} else if strings.HasPrefix(command, "r") {
+		if _, err := fmt.Sscanf(command, "r%d", &x); err != nil {
+			return browseCommand{}, errors.Wrap(err, "invalid number")
+		}
+		return browseCommand{action: "revert", x: x}, nil
+	} else if move, err := strconv.Atoi(command); len(command) == 1 && err == nil {
+		return browseCommand{action: "move", x: move}, nil
+	}
+	return browseCommand{}, errors.Errorf("unknown command: %s", command)
+}
+
+// execute runs the command on the browsed position, the position is left unchanged if it fails
+func (s *browseSession) execute(command browseCommand) error {
+	board := s.board
+	player := board.NextPlayer()
+	x := command.x
+	switch command.action {
+	case "help":
+		printBrowseHelp()
+	case "quit":
+		s.quit = true
+	case "board":
+		s.printBoard()
+	case "move":
+		if x < 0 || x >= board.W {
+			return errors.New("move number is out of range")
+		}
+		if !board.CanMakeMove(x) {
+			return errors.New("column is already full")
+		}
+		s.game.Play(x, common.NoMove)
+	case "pop":
+		if x < 0 || x >= board.W {
+			return errors.New("move number is out of range")
+		}
+		if !board.CanPlay(board.PopMove(x), player) {
+			return errors.New("can't pop out from the column")
+		}
+		s.game.Play(board.PopMove(x), common.NoMove)
+	case "play":
+		return s.playMoves(command.moves)
+	case "revert":
+		if x < 0 || x >= board.W {
+			return errors.New("move number is out of range")
+		}
+		if board.StackSize(x) == 0 {
+			return errors.New("column is already empty")
+		}
+		if board.GetCell(x, board.StackSize(x)-1) == common.Neutral {
+			return errors.New("neutral token can't be reverted")
+		}
+		board.Revert(x, board.StackSize(x)-1)
+		// token may be taken out of the moves order, so the history starts over
+		s.root = board.Clone()
+		s.game = common.NewGame(board)
+	case "new":
+		board.Clear()
+		s.root = board.Clone()
+		s.game = common.NewGame(board)
+	case "tree":
+		printCachedTree(os.Stdout, board, s.solver)
+	case "line":
+		move, ok := criticalMove(board, s.solver)
+		if !ok {
+			return errors.New("cache doesn't contain endings of the moves")
+		}
+		fmt.Printf("Critical move: %s\n", board.MoveString(move))
+		s.game.Play(move, common.NoMove)
+	case "back":
+		if _, ok := s.game.Undo(); !ok {
+			return errors.New("there is no parent position in the history")
+		}
+	case "mark":
+		s.bookmarks = append(s.bookmarks, newBookmark(s.root, s.game))
+		fmt.Printf("Bookmark %d: %s\n", len(s.bookmarks), s.bookmarks[len(s.bookmarks)-1].String())
+	case "marks":
+		for i, mark := range s.bookmarks {
+			fmt.Printf("Bookmark %d: %s\n", i+1, mark.String())
+		}
+	case "goto":
+		if x < 1 || x > len(s.bookmarks) {
+			return errors.New("bookmark number is out of range")
+		}
+		s.board, s.game = s.bookmarks[x-1].restore()
+		s.root = s.bookmarks[x-1].root.Clone()
+	case "clear_cache":
+		s.solver.Cache().ClearCache(uint(x))
+	case "clear_cache_from":
+		for d := uint(x); d < uint(board.W*board.H); d++ {
+			s.solver.Cache().ClearCache(d)
+		}
+	case "endings":
+		startTime := time.Now()
+		endings := s.solver.MovesEndings(board)
+		if endings == nil {
+			return errors.New("solving has been interrupted")
+		}
+		totalElapsed := time.Since(startTime)
+		logger := log.New(log.Ctx{
+			"solveTime": totalElapsed,
+			"endings":   endings,
+		})
+		logger.Info("Board solved", s.solver.SummaryVars())
+		printEndingsLine(board, endings, player)
+	case "cache":
+		showCacheStatistics(s.solver.Cache(), board.W, board.H)
+		depth := board.CountMoves()
+		cachedEndings := getCachedEndings(board, s.solver)
+		log.Debug("cache statistics", log.Ctx{
+			"depth":          depth,
+			"depthCacheSize": s.solver.Cache().DepthSize(depth),
+			"cachedEndings":  cachedEndings,
+		})
+		printGameEndingsLine(cachedEndings)
+	case "save":
+		return errors.Wrap(SaveCache(s.solver.Cache(), board), "saving cache")
+	case "retrain":
+		return retrainSolverDepth(board, s.solver, uint(x))
+	}
+	return nil
+}
+
+// playMoves makes consecutive moves in the CLI notation, eg. "0016" or "0p0" in PopOut variant
+func (s *browseSession) playMoves(moves string) error {
+	played := 0
+	for idx := 0; idx < len(moves); idx++ {
+		notation := moves[idx : idx+1]
+		if moves[idx] == 'p' && idx+1 < len(moves) {
+			idx++
+			notation = moves[idx-1 : idx+1]
+		}
+		move, err := s.board.ParseMove(notation)
+		if err == nil && !s.board.CanPlay(move, s.board.NextPlayer()) {
+			err = errors.New("move is not allowed")
+		}
+		if err != nil {
+			for ; played > 0; played-- {
+				s.game.Undo()
 			}
-			return "revert", x
-		} else if move, err := strconv.Atoi(command); len(command) == 1 && err == nil {
-			return "move", move
-		} else {
-			log.Error("Unknown command", log.Ctx{"command": command})
-			continue
+			return errors.Wrapf(err, "move %d of %s", idx+1, moves)
 		}
+		s.game.Play(move, common.NoMove)
+		played++
 	}
+	return nil
+}
+
+func printBrowseHelp() {
+	fmt.Println("Available commands:")
+	fmt.Println("  X, mX - move next player at column X [0-6], eg. m0")
+	fmt.Println("  rX - revert token at column X, eg. r0")
+	fmt.Println("  pX - pop out own token from the bottom of column X (PopOut variant), eg. p0")
+	fmt.Println("  play MOVES - make consecutive moves, eg. play 0016")
+	fmt.Println("  b - go back to the parent position")
+	fmt.Println("  e - evaluate endings")
+	fmt.Println("  c - show cache statistics & cached endings for current board")
+	fmt.Println("  t - list moves with cached endings, depth to the end (exact, range or lower bound) and number of cached descendants")
+	fmt.Println("  l - make the critical move, the first one preserving the result")
+	fmt.Println("  mark - bookmark current position")
+	fmt.Println("  marks - list bookmarks")
+	fmt.Println("  go X - jump to bookmark X")
+	fmt.Println("  board - show current board")
+	fmt.Println("  new - start new game")
+	fmt.Println("  clear X - clear cache at given depth")
+	fmt.Println("  clear X+ - clear cache from given depth")
+	fmt.Println("  retrain X - retrain worst scenarios until given depth")
+	fmt.Println("  save - save cache file")
+	fmt.Println("  q - quit")
 }
 
 func getCachedEndings(board *common.Board, solver common.IMoveSolver) []common.GameEnding {
-	endings := make([]common.GameEnding, board.W)
+	endings := make([]common.GameEnding, board.MoveSlots())
 	player := board.NextPlayer()
 	depth := board.CountMoves()
-	for move := 0; move < board.W; move++ {
-		if !board.CanMakeMove(move) {
+	for move := 0; move < board.MoveSlots(); move++ {
+		if !board.CanPlay(move, player) {
 			endings[move] = common.NoEnding
 			continue
 		}
 
-		moveY := board.Throw(move, player)
+		moveY := board.MakeMove(move, player)
 
 		ending, ok := solver.Cache().Get(board, depth)
 		if !ok {
@@ -211,7 +377,7 @@ func getCachedEndings(board *common.Board, solver common.IMoveSolver) []common.G
 			endings[move] = playerEnding
 		}
 
-		board.Revert(move, moveY)
+		board.UndoMove(move, moveY, player)
 	}
 	return endings
 }
@@ -219,23 +385,29 @@ func getCachedEndings(board *common.Board, solver common.IMoveSolver) []common.G
 func printGameEndingsLine(endings []common.GameEnding) {
 	displays := []string{}
 	for _, ending := range endings {
-		display := common.ShortGameEndingDisplays[ending]
+		display := common.Display.ShortEnding(ending)
 		displays = append(displays, display)
 	}
 	fmt.Println("| " + strings.Join(displays, " ") + " |")
 }
 
-func retrainSolverDepth(board *common.Board, solver common.IMoveSolver, maxDepth uint) {
+// retrainSolverDepth retrains the board until it's done or interrupted with SIGINT, returning the interruption error
+func retrainSolverDepth(board *common.Board, solver common.IMoveSolver, maxDepth uint) error {
 	log.Info("Retraining worst scenarios", log.Ctx{
 		"maxDepth": maxDepth,
 	})
 	startTime := time.Now()
-	solver.Retrain(board, maxDepth)
+	ctx, stop := common.InterruptContext(context.Background())
+	defer stop()
+	if err := solver.RetrainContext(ctx, board, maxDepth); err != nil {
+		return errors.Wrap(err, "retraining")
+	}
 	totalElapsed := time.Since(startTime)
 	logger := log.New(log.Ctx{
 		"solveTime": totalElapsed,
 	})
 	logger.Info("Training done", solver.SummaryVars())
+	return nil
 }
 
 func showCacheStatistics(cache common.ICache, boardW, boardH int) {
@@ -246,3 +418,36 @@ func showCacheStatistics(cache common.ICache, boardW, boardH int) {
 		})
 	}
 }
+
+// bookmark keeps the way to the position, so that going back works after jumping to it
+type bookmark struct {
+	root  *common.Board
+	moves []common.GameMove
+}
+
+func newBookmark(root *common.Board, game *common.Game) bookmark {
+	return bookmark{
+		root:  root.Clone(),
+		moves: append([]common.GameMove{}, game.Moves()...),
+	}
+}
+
+// restore plays the bookmarked moves from the root position
+func (b bookmark) restore() (*common.Board, *common.Game) {
+	board := b.root.Clone()
+	game := common.NewGame(board)
+	for _, move := range b.moves {
+		game.Play(move.Move, move.Ending)
+	}
+	return board, game
+}
+
+func (b bookmark) String() string {
+	moves := []string{}
+	for _, move := range b.moves {
+		moves = append(moves, b.root.MoveString(move.Move))
+	}
+	// root is the position after the last new, revert or jump, not necessarily an empty board
+	return fmt.Sprintf("%d moves from the root with %d tokens: %s",
+		len(b.moves), b.root.CountMoves(), strings.Join(moves, " "))
+}
@@ -0,0 +1,113 @@
+package solver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// browseStatement is a command of the browse script or a loop repeating its body for each value of the variable
+type browseStatement struct {
+	line    int
+	command string
+
+	variable string
+	values   []string
+	body     []browseStatement
+}
+
+// parseBrowseScript reads browse commands, one per line. Lines starting with # are comments.
+// "for NAME in VALUE..." repeats the lines up to the matching "end", replacing $NAME with consecutive values.
+func parseBrowseScript(in io.Reader) ([]browseStatement, error) {
+	scanner := bufio.NewScanner(in)
+	// statements of the enclosing blocks, the last one is the innermost block
+	blocks := [][]browseStatement{{}}
+	loops := []browseStatement{}
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if fields[0] == "for" {
+			if len(fields) < 4 || fields[2] != "in" {
+				return nil, errors.Errorf("line %d: invalid loop, expected: for NAME in VALUE...", lineNumber)
+			}
+			loops = append(loops, browseStatement{line: lineNumber, variable: fields[1], values: fields[3:]})
+			blocks = append(blocks, []browseStatement{})
+			continue
+		}
+		if line == "end" {
+			if len(loops) == 0 {
+				return nil, errors.Errorf("line %d: end without a loop", lineNumber)
+			}
+			loop := loops[len(loops)-1]
+			loop.body = blocks[len(blocks)-1]
+			loops = loops[:len(loops)-1]
+			blocks = blocks[:len(blocks)-1]
+			blocks[len(blocks)-1] = append(blocks[len(blocks)-1], loop)
+			continue
+		}
+		if _, err := parseBrowseCommand(line); err != nil && !strings.Contains(line, "$") {
+			return nil, errors.Wrapf(err, "line %d", lineNumber)
+		}
+		blocks[len(blocks)-1] = append(blocks[len(blocks)-1], browseStatement{line: lineNumber, command: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading script")
+	}
+	if len(loops) > 0 {
+		return nil, errors.Errorf("line %d: loop is not closed with end", loops[len(loops)-1].line)
+	}
+	return blocks[0], nil
+}
+
+// run executes the statements with loop variables set, stopping at the first failing command
+func (s *browseSession) run(statements []browseStatement, vars map[string]string) error {
+	for _, statement := range statements {
+		if s.quit {
+			return nil
+		}
+		if statement.variable != "" {
+			for _, value := range statement.values {
+				loopVars := map[string]string{statement.variable: value}
+				for name, v := range vars {
+					if name != statement.variable {
+						loopVars[name] = v
+					}
+				}
+				if err := s.run(statement.body, loopVars); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		undefined := []string{}
+		line := os.Expand(statement.command, func(name string) string {
+			value, ok := vars[name]
+			if !ok {
+				undefined = append(undefined, name)
+			}
+			return value
+		})
+		if len(undefined) > 0 {
+			return errors.Errorf("line %d: undefined variable: %s", statement.line, strings.Join(undefined, ", "))
+		}
+		fmt.Printf("> %s\n", line)
+		command, err := parseBrowseCommand(line)
+		if err != nil {
+			return errors.Wrapf(err, "line %d", statement.line)
+		}
+		if err := s.execute(command); err != nil {
+			return errors.Wrapf(err, "line %d: %s", statement.line, line)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package solver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/igrek51/connect4solver/solver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBrowseSession() *browseSession {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3))
+	return newBrowseSession(board, CreateSolver(board))
+}
+
+func TestParseBrowseCommand(t *testing.T) {
+	for input, expected := range map[string]browseCommand{
+		"3":         {action: "move", x: 3},
+		"m 2":       {action: "move", x: 2},
+		"p1":        {action: "pop", x: 1},
+		"play 0016": {action: "play", moves: "0016"},
+		"clear 5+":  {action: "clear_cache_from", x: 5},
+		"go 2":      {action: "goto", x: 2},
+		"q":         {action: "quit"},
+	} {
+		command, err := parseBrowseCommand(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, command, input)
+	}
+	_, err := parseBrowseCommand("retrain x")
+	assert.Error(t, err)
+	_, err = parseBrowseCommand("unknown")
+	assert.EqualError(t, err, "unknown command: unknown")
+}
+
+func TestBrowseScriptLoops(t *testing.T) {
+	statements, err := parseBrowseScript(strings.NewReader(`
+# moves of both players in each column
+for x in 0 1
+  for y in 2 3
+    play $x$y
+  end
+end
+back
+`))
+	assert.NoError(t, err)
+	assert.Len(t, statements, 2)
+
+	session := newTestBrowseSession()
+	assert.NoError(t, session.run(statements, map[string]string{}))
+	assert.Len(t, session.game.Moves(), 7)
+	assert.Equal(t, PlayerA, session.board.GetCell(1, 1))
+	assert.Equal(t, Empty, session.board.GetCell(3, 1))
+}
+
+func TestBrowseScriptErrors(t *testing.T) {
+	for script, expected := range map[string]string{
+		"for x in 0 1\nplay $x":        "line 1: loop is not closed with end",
+		"new\nend":                     "line 2: end without a loop",
+		"new\nfor x 0 1\nend":          "line 2: invalid loop, expected: for NAME in VALUE...",
+		"new\n\nunknown":               "line 3: unknown command: unknown",
+		"retrain 2\nclear x+":          "line 2: invalid number: expected integer",
+		"for x in 0 1\n  play $y\nend": "",
+	} {
+		_, err := parseBrowseScript(strings.NewReader(script))
+		if expected == "" {
+			assert.NoError(t, err, script)
+		} else {
+			assert.EqualError(t, err, expected, script)
+		}
+	}
+
+	session := newTestBrowseSession()
+	statements, err := parseBrowseScript(strings.NewReader("play 00\nfor x in 0 1\n  play $y\nend"))
+	assert.NoError(t, err)
+	assert.EqualError(t, session.run(statements, map[string]string{}), "line 3: undefined variable: y")
+
+	session = newTestBrowseSession()
+	statements, err = parseBrowseScript(strings.NewReader("play 00\nplay 1000\nplay 3"))
+	assert.NoError(t, err)
+	assert.EqualError(t, session.run(statements, map[string]string{}),
+		"line 2: play 1000: move 4 of 1000: move is not allowed")
+	assert.Len(t, session.game.Moves(), 2, "failed moves are taken back")
+	assert.Equal(t, Empty, session.board.GetCell(1, 0))
+}
+
+func TestBrowseScriptQuit(t *testing.T) {
+	statements, err := parseBrowseScript(strings.NewReader("0\nq\n1"))
+	assert.NoError(t, err)
+	session := newTestBrowseSession()
+	assert.NoError(t, session.run(statements, map[string]string{}))
+	assert.Len(t, session.game.Moves(), 1)
+}
+
+func TestBrowseScriptSaveError(t *testing.T) {
+	// cache directory can't be created under a regular file
+	file, err := ioutil.TempFile("", "c4-cache-dir")
+	assert.NoError(t, err)
+	file.Close()
+	defer os.Remove(file.Name())
+	defer func(dir string) { CacheDir = dir }(CacheDir)
+	CacheDir = filepath.Join(file.Name(), "cache")
+
+	statements, err := parseBrowseScript(strings.NewReader("0\nsave\n1"))
+	assert.NoError(t, err)
+	session := newTestBrowseSession()
+	err = session.run(statements, map[string]string{})
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "line 2: save: saving cache: "), err.Error())
+	}
+	assert.Len(t, session.game.Moves(), 1, "script stops at the failing command")
+}
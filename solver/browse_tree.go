@@ -0,0 +1,318 @@
+package solver
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// MaxTreeDescendants limits counting of cached descendants, so that browsing large caches stays responsive
+const MaxTreeDescendants = 1_000_000
+
+// treeChild describes a move from the browsed position and what the cache knows about the game tree below it
+type treeChild struct {
+	Move int
+	// Ending is the cached ending after the move, NoMove if unknown
+	Ending common.Player
+	// Depth bounds a number of moves to the end of the game including the move itself,
+	// when the winner takes the shortest win and the loser the longest defence
+	Depth endDistance
+	// Descendants is a number of distinct cached positions reachable through cached positions, including the child
+	Descendants int
+}
+
+type treePosition struct {
+	state      common.BoardKey
+	nextPlayer common.Player
+}
+
+func newTreePosition(board *common.Board) treePosition {
+	return treePosition{state: board.State, nextPlayer: board.NextPlayer()}
+}
+
+// cachedTreeChildren describes all possible moves from the position using the cache only
+func cachedTreeChildren(board *common.Board, solver common.IMoveSolver) []treeChild {
+	board = board.Clone()
+	player := board.NextPlayer()
+	depth := board.CountMoves()
+	endings := getKnownEndings(board, solver)
+	distances := newDistanceSearch(solver)
+	children := []treeChild{}
+	for move := 0; move < board.MoveSlots(); move++ {
+		if !board.CanPlay(move, player) {
+			continue
+		}
+		child := treeChild{Move: move, Ending: endings[move]}
+		y := board.MakeMove(move, player)
+		if winner := moveWinner(solver, board, move, y, player); winner != common.Empty {
+			child.Ending = winner
+			child.Depth = exactDistance(1)
+		} else if isATie(board) {
+			child.Ending = common.Empty
+			child.Depth = exactDistance(1)
+		} else if _, ok := solver.Cache().Get(board, depth); ok {
+			child.Depth = distances.distance(board, child.Ending).after(1)
+			visited := map[treePosition]bool{newTreePosition(board): true}
+			countCachedDescendants(board, solver, visited)
+			child.Descendants = len(visited)
+		}
+		board.UndoMove(move, y, player)
+		children = append(children, child)
+	}
+	return children
+}
+
+// unboundedDistance is an upper bound of a distance that can't be limited by the cache
+const unboundedDistance = math.MaxInt32
+
+// endDistance bounds a number of moves to the end of the game, min equals max when the distance is exact
+type endDistance struct {
+	min int
+	max int
+}
+
+func exactDistance(moves int) endDistance {
+	return endDistance{min: moves, max: moves}
+}
+
+func (d endDistance) after(moves int) endDistance {
+	if d.max == unboundedDistance {
+		return endDistance{min: d.min + moves, max: unboundedDistance}
+	}
+	return endDistance{min: d.min + moves, max: d.max + moves}
+}
+
+// String shows "N" for exact distance, "N-M" for a range or ">=N" when the cache doesn't limit it
+func (d endDistance) String() string {
+	switch {
+	case d.min == d.max:
+		return fmt.Sprint(d.min)
+	case d.max == unboundedDistance:
+		return fmt.Sprintf(">=%d", d.min)
+	default:
+		return fmt.Sprintf("%d-%d", d.min, d.max)
+	}
+}
+
+// distanceSearch bounds distances to the end of the game when the winner takes the shortest win
+// and the loser the longest defence. Moves missing from the cache may lead to a shorter win or a longer defence,
+// so they widen the bounds instead of being ignored.
+type distanceSearch struct {
+	solver  common.IMoveSolver
+	known   map[treePosition]endDistance
+	pending map[treePosition]bool
+}
+
+func newDistanceSearch(solver common.IMoveSolver) *distanceSearch {
+	return &distanceSearch{
+		solver:  solver,
+		known:   map[treePosition]endDistance{},
+		pending: map[treePosition]bool{},
+	}
+}
+
+// distance bounds the distance from the position of given ending,
+// it stops at positions missing from the cache, repeated positions and after MaxTreeDescendants positions
+func (d *distanceSearch) distance(board *common.Board, ending common.Player) endDistance {
+	unknown := endDistance{min: 1, max: unboundedDistance}
+	if ending == common.Empty {
+		if board.Variant == common.PopOutVariant {
+			return unknown // positions may repeat forever
+		}
+		// players keeping a tie can't end the game before the board is full
+		return exactDistance(board.PlayableCells() - int(board.CountMoves()))
+	}
+	position := newTreePosition(board)
+	if known, ok := d.known[position]; ok {
+		return known
+	}
+	if d.pending[position] || len(d.known) >= MaxTreeDescendants {
+		return unknown
+	}
+	d.pending[position] = true
+	defer delete(d.pending, position)
+
+	player := board.NextPlayer()
+	winning := ending == player
+	endings := getKnownEndings(board, d.solver)
+	var best *endDistance
+	for move, moveEnding := range endings {
+		if !board.CanPlay(move, player) {
+			continue
+		}
+		if moveEnding == common.NoMove {
+			moveEnding = d.opponentWin(board, move, player)
+		}
+		var moveDistance endDistance
+		if moveEnding == common.NoMove {
+			if !winning {
+				moveDistance = unknown
+			} else if shortest, ok := d.shortestUnknownWin(board, move, player); ok {
+				moveDistance = endDistance{min: shortest, max: unboundedDistance}
+			} else {
+				continue
+			}
+		} else if moveEnding != ending {
+			continue
+		} else {
+			y := board.MakeMove(move, player)
+			moveDistance = exactDistance(1)
+			if moveWinner(d.solver, board, move, y, player) == common.Empty && !isATie(board) {
+				moveDistance = d.distance(board, ending).after(1)
+			}
+			board.UndoMove(move, y, player)
+		}
+
+		if best == nil {
+			best = &moveDistance
+		} else if winning {
+			// any of the winning moves may turn out to be the shortest one
+			if moveDistance.min < best.min {
+				best.min = moveDistance.min
+			}
+			if moveDistance.max < best.max {
+				best.max = moveDistance.max
+			}
+		} else {
+			if moveDistance.min > best.min {
+				best.min = moveDistance.min
+			}
+			if moveDistance.max > best.max {
+				best.max = moveDistance.max
+			}
+		}
+	}
+	if best == nil {
+		return unknown
+	}
+	d.known[position] = *best
+	return *best
+}
+
+// shortestUnknownWin tells the shortest possible win through the move missing from the cache,
+// which can't be an immediate win, because these are recognized by the referee
+func (d *distanceSearch) shortestUnknownWin(board *common.Board, move int, player common.Player) (int, bool) {
+	y := board.MakeMove(move, player)
+	defer board.UndoMove(move, y, player)
+	if moveWinner(d.solver, board, move, y, player) != common.Empty || isATie(board) {
+		return 0, false
+	}
+	if board.NextPlayer() == player {
+		return 2, true
+	}
+	return 3, true
+}
+
+// opponentWin tells the ending of the move missing from the cache, when the opponent has a known winning reply,
+// eg. a move ignoring a forced block, which isn't cached by the solver
+func (d *distanceSearch) opponentWin(board *common.Board, move int, player common.Player) common.Player {
+	ending := common.NoMove
+	y := board.MakeMove(move, player)
+	if moveWinner(d.solver, board, move, y, player) == common.Empty && !isATie(board) &&
+		getCachedWinningEndings(board, d.solver) != nil {
+		ending = board.NextPlayer()
+	}
+	board.UndoMove(move, y, player)
+	return ending
+}
+
+// criticalMove finds the first move preserving the result of the position according to the cache
+func criticalMove(board *common.Board, solver common.IMoveSolver) (int, bool) {
+	player := board.NextPlayer()
+	endings := getKnownEndings(board, solver)
+	known := false
+	for _, ending := range endings {
+		known = known || ending != common.NoMove
+	}
+	if !known {
+		return 0, false
+	}
+	result := bestEnding(endings, player)
+	for move, ending := range endings {
+		if ending == result && board.CanPlay(move, player) {
+			return move, true
+		}
+	}
+	return 0, false
+}
+
+// criticalLine follows critical moves until the end of the game or the cache.
+// It tells if the end of the game has been reached, the line is broken when a position repeats.
+func criticalLine(board *common.Board, solver common.IMoveSolver) ([]int, bool) {
+	board = board.Clone()
+	visited := map[treePosition]bool{newTreePosition(board): true}
+	line := []int{}
+	for {
+		move, ok := criticalMove(board, solver)
+		if !ok {
+			return line, false
+		}
+		player := board.NextPlayer()
+		y := board.MakeMove(move, player)
+		line = append(line, move)
+		if moveWinner(solver, board, move, y, player) != common.Empty || isATie(board) {
+			return line, true
+		}
+		position := newTreePosition(board)
+		if visited[position] {
+			return line, false
+		}
+		visited[position] = true
+	}
+}
+
+// countCachedDescendants marks positions reachable from the board through cached positions only
+func countCachedDescendants(board *common.Board, solver common.IMoveSolver, visited map[treePosition]bool) {
+	player := board.NextPlayer()
+	depth := board.CountMoves()
+	for move := 0; move < board.MoveSlots() && len(visited) < MaxTreeDescendants; move++ {
+		if !board.CanPlay(move, player) {
+			continue
+		}
+		y := board.MakeMove(move, player)
+		if moveWinner(solver, board, move, y, player) == common.Empty {
+			if _, ok := solver.Cache().Get(board, depth); ok {
+				position := newTreePosition(board)
+				if !visited[position] {
+					visited[position] = true
+					countCachedDescendants(board, solver, visited)
+				}
+			}
+		}
+		board.UndoMove(move, y, player)
+	}
+}
+
+// printCachedTree lists children of the position with their cached verdicts and the critical line
+func printCachedTree(out io.Writer, board *common.Board, solver common.IMoveSolver) {
+	player := board.NextPlayer()
+	fmt.Fprintln(out, "Move Ending  Depth  Cached")
+	for _, child := range cachedTreeChildren(board, solver) {
+		ending := common.NoEnding
+		depth := "-"
+		if child.Ending != common.NoMove {
+			ending = common.EndingForPlayer(child.Ending, player)
+			depth = child.Depth.String()
+		}
+		descendants := common.BigintSeparated(uint64(child.Descendants))
+		if child.Descendants >= MaxTreeDescendants {
+			descendants += "+"
+		}
+		fmt.Fprintf(out, "%4s %s%s %6s %7s\n", board.MoveString(child.Move),
+			common.Display.Ending(ending), strings.Repeat(" ", 6-len(ending)), depth, descendants)
+	}
+
+	line, complete := criticalLine(board, solver)
+	moves := []string{}
+	for _, move := range line {
+		moves = append(moves, board.MoveString(move))
+	}
+	suffix := ""
+	if !complete {
+		suffix = " ..."
+	}
+	fmt.Fprintf(out, "Critical line: %s%s\n", strings.Join(moves, " "), suffix)
+}
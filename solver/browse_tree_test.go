@@ -0,0 +1,107 @@
+package solver
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/igrek51/connect4solver/solver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedTreeChildren(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3))
+	solver := CreateSolver(board)
+	endings := solver.MovesEndings(board)
+
+	children := cachedTreeChildren(board, solver)
+	assert.Len(t, children, 4)
+	for _, child := range children {
+		assert.Equal(t, endings[child.Move], child.Ending)
+		assert.True(t, child.Descendants > 0)
+		// the first player can't win before making 3 moves
+		assert.GreaterOrEqual(t, child.Depth.min, 5, "move %d", child.Move)
+		assert.GreaterOrEqual(t, child.Depth.max, child.Depth.min, "move %d", child.Move)
+		assert.LessOrEqual(t, child.Depth.max, 16, "move %d", child.Move)
+	}
+
+	move, ok := criticalMove(board, solver)
+	assert.True(t, ok)
+	assert.Equal(t, bestEnding(endings, PlayerA), endings[move])
+	line, _ := criticalLine(board, solver)
+	assert.Equal(t, move, line[0])
+
+	out := &bytes.Buffer{}
+	printCachedTree(out, board, solver)
+	assert.Contains(t, out.String(), "Move Ending  Depth  Cached\n")
+	assert.Contains(t, out.String(), "Critical line: ")
+}
+
+func TestCachedTreeShortestWin(t *testing.T) {
+	board := ParseBoard(`
+	....
+	....
+	A...
+	AB.B
+	`, WithWinStreak(3))
+	solver := CreateSolver(board)
+	endings := solver.MovesEndings(board)
+	assert.Equal(t, PlayerA, endings[2])
+
+	children := cachedTreeChildren(board, solver)
+	assert.Equal(t, treeChild{Move: 0, Ending: PlayerA, Depth: exactDistance(1)}, children[0])
+	// B defends against the immediate win, so winning with another move takes longer
+	assert.Equal(t, PlayerA, children[2].Ending)
+	assert.Greater(t, children[2].Depth.min, 1)
+}
+
+func TestCachedTreeExactDepth(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3)).ApplyMoves("1230")
+	solver := CreateSolver(board)
+	solver.MovesEndings(board)
+
+	children := cachedTreeChildren(board, solver)
+	// all defences of B are cached, so the shortest win is known exactly
+	assert.Equal(t, exactDistance(5), children[1].Depth)
+	assert.Equal(t, PlayerA, children[2].Ending)
+	assert.Equal(t, endDistance{min: 7, max: 9}, children[2].Depth)
+
+	out := &bytes.Buffer{}
+	printCachedTree(out, board, solver)
+	assert.Contains(t, out.String(), "   1 Win         5 ")
+	assert.Contains(t, out.String(), "   2 Win       7-9 ")
+}
+
+func TestEndDistanceString(t *testing.T) {
+	assert.Equal(t, "5", exactDistance(5).String())
+	assert.Equal(t, "7-9", endDistance{min: 7, max: 9}.String())
+	assert.Equal(t, ">=4", endDistance{min: 4, max: unboundedDistance}.String())
+	assert.Equal(t, ">=6", endDistance{min: 5, max: unboundedDistance}.after(1).String())
+}
+
+func TestCachedTreeUnknownPosition(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3))
+	solver := CreateSolver(board)
+
+	_, ok := criticalMove(board, solver)
+	assert.False(t, ok)
+	for _, child := range cachedTreeChildren(board, solver) {
+		assert.Equal(t, NoMove, child.Ending)
+		assert.Equal(t, 0, child.Descendants)
+	}
+}
+
+func TestBookmarkRestore(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3))
+	game := NewGame(board)
+	game.Play(1, NoMove)
+	game.Play(2, NoMove)
+	mark := newBookmark(NewBoard(WithSize(4, 4), WithWinStreak(3)), game)
+	game.Play(3, NoMove)
+
+	restored, restoredGame := mark.restore()
+	assert.Equal(t, PlayerA, restored.GetCell(1, 0))
+	assert.Equal(t, PlayerB, restored.GetCell(2, 0))
+	assert.Equal(t, Empty, restored.GetCell(3, 0))
+	assert.Len(t, restoredGame.Moves(), 2)
+	assert.Equal(t, "2 moves from the root with 0 tokens: 1 2", mark.String())
+}
@@ -0,0 +1,96 @@
+package solver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+const (
+	// CacheDirEnv is an environment variable setting directory of cache files
+	CacheDirEnv = "C4SOLVER_CACHE_DIR"
+	// LocalCacheDir is a cache directory relative to the working dir, used when running from the repository
+	LocalCacheDir = "cache"
+)
+
+// CacheDir is a directory of cache files
+var CacheDir = LocalCacheDir
+
+// CacheFile overrides path of the cache file, regardless of the board
+var CacheFile = ""
+
+// CacheReadOnly prevents overwriting cache file, so it can be shared by many sessions
+var CacheReadOnly = false
+
+// CacheFileFormat is a format of saved cache files, loading detects the format by itself
+var CacheFileFormat = common.ProtobufCacheFormat
+
+// ResolveCacheDir chooses cache directory: given one, the one from environment variable,
+// local "cache" directory if it exists or user cache directory ($XDG_CACHE_HOME/c4solver or ~/.cache/c4solver)
+func ResolveCacheDir(dir string) string {
+	if dir != "" {
+		return dir
+	}
+	if envDir := os.Getenv(CacheDirEnv); envDir != "" {
+		return envDir
+	}
+	if info, err := os.Stat(LocalCacheDir); err == nil && info.IsDir() {
+		return LocalCacheDir
+	}
+	userDir, err := os.UserCacheDir()
+	if err != nil {
+		return LocalCacheDir
+	}
+	return filepath.Join(userDir, "c4solver")
+}
+
+func CacheFilename(board *common.Board) string {
+	if CacheFile != "" {
+		return CacheFile
+	}
+	name := fmt.Sprintf("cache_%dx%d%s.protobuf", board.W, board.H, common.CacheIdentitySuffix(board))
+	return filepath.Join(CacheDir, name)
+}
+
+// SaveCache saves cached endings to the cache file of the board, unless the cache is read-only
+func SaveCache(cache common.ICache, board *common.Board) error {
+	if CacheReadOnly {
+		log.Warn("Cache is read-only, skipping saving", log.Ctx{"filename": CacheFilename(board)})
+		return nil
+	}
+	return SaveCacheFile(cache, CacheFilename(board))
+}
+
+// SaveCacheFile saves cached endings (up to the half of the max cached depth) to given file in CacheFileFormat
+func SaveCacheFile(cache common.ICache, filename string) error {
+	return common.SaveCacheFileFormat(cache, filename, CacheFileFormat, log.Root())
+}
+
+func LoadCache(cache common.ICache, board *common.Board) error {
+	return common.LoadCacheFile(cache, CacheFilename(board), log.Root())
+}
+
+func MustSaveCache(cache common.ICache, board *common.Board) {
+	err := SaveCache(cache, board)
+	if err != nil {
+		panic(errors.Wrap(err, "saving cache"))
+	}
+}
+
+func MustLoadCache(cache common.ICache, board *common.Board) {
+	err := LoadCache(cache, board)
+	if err != nil {
+		panic(errors.Wrap(err, "loading cache"))
+	}
+}
+
+func CacheFileExists(board *common.Board) bool {
+	filename := CacheFilename(board)
+	_, err := os.Stat(filename)
+	return err == nil
+}
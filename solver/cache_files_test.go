@@ -0,0 +1,50 @@
+package solver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/igrek51/connect4solver/solver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheFilename(t *testing.T) {
+	defer func(dir, file string) { CacheDir, CacheFile = dir, file }(CacheDir, CacheFile)
+	board := NewBoard(WithSize(7, 6), WithVariant(MisereVariant))
+
+	CacheDir = "/var/cache/c4"
+	assert.Equal(t, "/var/cache/c4/cache_7x6_misere.protobuf", CacheFilename(board))
+
+	CacheFile = "experiment.protobuf"
+	assert.Equal(t, "experiment.protobuf", CacheFilename(board))
+}
+
+func TestResolveCacheDir(t *testing.T) {
+	workDir, err := os.Getwd()
+	assert.NoError(t, err)
+	defer os.Chdir(workDir)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Setenv(CacheDirEnv, os.Getenv(CacheDirEnv))
+	defer os.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+
+	os.Setenv("XDG_CACHE_HOME", "/home/user/.cache")
+	os.Setenv(CacheDirEnv, "")
+	assert.Equal(t, "/home/user/.cache/c4solver", ResolveCacheDir(""))
+
+	assert.NoError(t, os.Mkdir(LocalCacheDir, 0755))
+	assert.Equal(t, LocalCacheDir, ResolveCacheDir(""))
+
+	os.Setenv(CacheDirEnv, "/shared/cache")
+	assert.Equal(t, "/shared/cache", ResolveCacheDir(""))
+	assert.Equal(t, "/tmp/experiment", ResolveCacheDir("/tmp/experiment"))
+}
+
+func TestReadOnlyCacheIsNotSaved(t *testing.T) {
+	defer func(file string, readOnly bool) { CacheFile, CacheReadOnly = file, readOnly }(CacheFile, CacheReadOnly)
+	CacheFile = filepath.Join(t.TempDir(), "cache.protobuf")
+	CacheReadOnly = true
+
+	assert.NoError(t, SaveCache(nil, NewBoard()))
+	assert.NoFileExists(t, CacheFile)
+}
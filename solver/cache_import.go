@@ -0,0 +1,163 @@
+package solver
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// ChecksumManifest is a file published along with cache files, listing their SHA-256 sums (sha256sum format)
+const ChecksumManifest = "SHA256SUMS"
+
+var partSuffixRegex = regexp.MustCompile(`^\.(\d+)$`)
+
+// importCache fetches cache file (possibly gzipped and split into numbered parts) from local directory or HTTP mirror,
+// verifies checksums of the parts, decompresses and validates the content, then installs it to the output file
+func importCache(source string, name string, output string) error {
+	manifestBytes, err := fetchMirrorFile(source, ChecksumManifest)
+	if err != nil {
+		return errors.Wrap(err, "fetching checksum manifest")
+	}
+	checksums, err := parseChecksumManifest(manifestBytes)
+	if err != nil {
+		return errors.Wrap(err, "parsing checksum manifest")
+	}
+	parts, gzipped, err := findCacheParts(checksums, name)
+	if err != nil {
+		return err
+	}
+
+	data := []byte{}
+	for _, part := range parts {
+		partBytes, err := fetchMirrorFile(source, part)
+		if err != nil {
+			return errors.Wrapf(err, "fetching %s", part)
+		}
+		sum := sha256.Sum256(partBytes)
+		if hex.EncodeToString(sum[:]) != checksums[part] {
+			return fmt.Errorf("checksum mismatch of %s", part)
+		}
+		log.Info("Part verified", log.Ctx{"part": part, "bytes": len(partBytes)})
+		data = append(data, partBytes...)
+	}
+
+	if gzipped {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return errors.Wrap(err, "reading gzip stream")
+		}
+		data, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return errors.Wrap(err, "decompressing cache")
+		}
+	}
+	if err := common.ValidateCacheData(data); err != nil {
+		return errors.Wrap(err, "invalid cache file")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return errors.Wrap(err, "creating cache directory")
+	}
+	// write to temporary file first, so that broken import doesn't leave a partial cache file
+	tmpFile := output + ".tmp"
+	if err := ioutil.WriteFile(tmpFile, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write to file")
+	}
+	if err := os.Rename(tmpFile, output); err != nil {
+		return errors.Wrap(err, "installing cache file")
+	}
+	log.Info("Cache imported", log.Ctx{
+		"filename": output,
+		"parts":    len(parts),
+		"bytes":    len(data),
+	})
+	return nil
+}
+
+// fetchMirrorFile reads file from HTTP(S) base URL or local directory
+func fetchMirrorFile(source string, name string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return ioutil.ReadFile(filepath.Join(source, name))
+	}
+	url := strings.TrimSuffix(source, "/") + "/" + name
+	response, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status of %s: %s", url, response.Status)
+	}
+	return ioutil.ReadAll(response.Body)
+}
+
+// parseChecksumManifest reads lines of "<sha256 hex> <filename>" into map of checksums by filename
+func parseChecksumManifest(manifest []byte) (map[string]string, error) {
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(fields[0]) != 2*sha256.Size {
+			return nil, fmt.Errorf("invalid manifest line: %s", line)
+		}
+		// binary mode of sha256sum marks filenames with asterisk
+		checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return checksums, scanner.Err()
+}
+
+// findCacheParts finds files making up the cache file in the manifest: the file itself or its numbered parts,
+// optionally gzipped (eg. cache_7x6.protobuf.gz.001, cache_7x6.protobuf.gz.002)
+func findCacheParts(checksums map[string]string, name string) ([]string, bool, error) {
+	for _, gzipped := range []bool{false, true} {
+		base := name
+		if gzipped {
+			base += ".gz"
+		}
+		if _, ok := checksums[base]; ok {
+			return []string{base}, gzipped, nil
+		}
+		parts := []string{}
+		for filename := range checksums {
+			if strings.HasPrefix(filename, base) && partSuffixRegex.MatchString(filename[len(base):]) {
+				parts = append(parts, filename)
+			}
+		}
+		if len(parts) > 0 {
+			// parts have zero-padded numbers, but sort them by length first in case they're not
+			sort.Slice(parts, func(i, j int) bool {
+				if len(parts[i]) != len(parts[j]) {
+					return len(parts[i]) < len(parts[j])
+				}
+				return parts[i] < parts[j]
+			})
+			for i, part := range parts {
+				number := partSuffixRegex.FindStringSubmatch(part[len(base):])[1]
+				if strings.TrimLeft(number, "0") != fmt.Sprint(i+1) {
+					return nil, false, fmt.Errorf("part %d of %s is missing in checksum manifest", i+1, base)
+				}
+			}
+			return parts, gzipped, nil
+		}
+	}
+	return nil, false, fmt.Errorf("%s not found in checksum manifest", name)
+}
@@ -0,0 +1,106 @@
+package solver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	. "github.com/igrek51/connect4solver/solver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// publishCache writes cache content to the mirror directory as parts along with checksum manifest
+func publishCache(t *testing.T, dir string, parts map[string][]byte) {
+	manifest := ""
+	for name, content := range parts {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), content, 0644))
+		sum := sha256.Sum256(content)
+		manifest += fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), name)
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ChecksumManifest), []byte(manifest), 0644))
+}
+
+func trainedCacheData(t *testing.T) []byte {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3))
+	solver := CreateSolver(board)
+	solver.MovesEndings(board)
+	filename := filepath.Join(t.TempDir(), "cache.protobuf")
+	assert.NoError(t, SaveCacheFile(solver.Cache(), filename))
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	return data
+}
+
+func TestImportCacheFromHttpMirror(t *testing.T) {
+	data := trainedCacheData(t)
+	gzipped := &bytes.Buffer{}
+	writer := gzip.NewWriter(gzipped)
+	writer.Write(data)
+	writer.Close()
+	compressed := gzipped.Bytes()
+	third := len(compressed) / 3
+
+	mirror := t.TempDir()
+	publishCache(t, mirror, map[string][]byte{
+		"cache_4x4.protobuf.gz.001": compressed[:third],
+		"cache_4x4.protobuf.gz.002": compressed[third : 2*third],
+		"cache_4x4.protobuf.gz.003": compressed[2*third:],
+	})
+	server := httptest.NewServer(http.FileServer(http.Dir(mirror)))
+	defer server.Close()
+
+	output := filepath.Join(t.TempDir(), "cache", "cache_4x4.protobuf")
+	assert.NoError(t, importCache(server.URL, "cache_4x4.protobuf", output))
+	imported, err := ioutil.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Equal(t, data, imported)
+
+	assert.Error(t, importCache(server.URL, "cache_5x4.protobuf", output))
+}
+
+func TestImportCacheFromLocalDirectory(t *testing.T) {
+	data := trainedCacheData(t)
+	mirror := t.TempDir()
+	publishCache(t, mirror, map[string][]byte{"cache_4x4.protobuf": data})
+	output := filepath.Join(t.TempDir(), "cache_4x4.protobuf")
+
+	assert.NoError(t, importCache(mirror, "cache_4x4.protobuf", output))
+	imported, err := ioutil.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Equal(t, data, imported)
+}
+
+func TestImportCacheRejectsCorruptedFiles(t *testing.T) {
+	data := trainedCacheData(t)
+	output := filepath.Join(t.TempDir(), "cache_4x4.protobuf")
+
+	mirror := t.TempDir()
+	publishCache(t, mirror, map[string][]byte{"cache_4x4.protobuf": data})
+	corrupted := append([]byte{}, data...)
+	corrupted[10] ^= 0xff
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(mirror, "cache_4x4.protobuf"), corrupted, 0644))
+	err := importCache(mirror, "cache_4x4.protobuf", output)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+
+	mirror = t.TempDir()
+	publishCache(t, mirror, map[string][]byte{"cache_4x4.protobuf": []byte("C4CC\x01\x00\x05\x09")})
+	err = importCache(mirror, "cache_4x4.protobuf", output)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid cache file")
+
+	mirror = t.TempDir()
+	publishCache(t, mirror, map[string][]byte{"cache_4x4.protobuf.001": data[:10], "cache_4x4.protobuf.003": data[10:]})
+	err = importCache(mirror, "cache_4x4.protobuf", output)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "part 2")
+
+	assert.NoFileExists(t, output)
+}
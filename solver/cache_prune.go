@@ -0,0 +1,105 @@
+package solver
+
+import (
+	"fmt"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// strategyPruner collects cache entries reachable when the chosen side follows a single strategy
+// and the opponent plays anything
+type strategyPruner struct {
+	solver common.IMoveSolver
+	target common.ICache
+	side   common.Player
+}
+
+// pruneCache copies the entries of strategy chosen for the side (Empty - the winner of the start position) to the target cache,
+// returns the side following the strategy
+func pruneCache(
+	board *common.Board, solver common.IMoveSolver, target common.ICache, side common.Player,
+) (common.Player, error) {
+	board = board.Clone()
+	pruner := &strategyPruner{
+		solver: solver,
+		target: target,
+		side:   side,
+	}
+	if side == common.Empty {
+		player := board.NextPlayer()
+		endings := getKnownEndings(board, solver)
+		for move := 0; move < board.MoveSlots(); move++ {
+			if board.CanPlay(move, player) && endings[move] == common.NoMove {
+				return side, fmt.Errorf("cache doesn't contain ending of the start move %s", board.MoveString(move))
+			}
+		}
+		switch bestEnding(endings, player) {
+		case common.PlayerB:
+			pruner.side = common.PlayerB
+		default: // a tie is kept by the first player
+			pruner.side = common.PlayerA
+		}
+	}
+	pruner.visit(board)
+	return pruner.side, nil
+}
+
+// visit keeps the best move of the strategy side or all moves of the opponent, then goes deeper into kept positions
+func (p *strategyPruner) visit(board *common.Board) {
+	player := board.NextPlayer()
+	depth := board.CountMoves()
+	endings := getKnownEndings(board, p.solver)
+
+	moves := []int{}
+	if player == p.side {
+		if best := p.strategyMove(board, endings, player); best >= 0 {
+			moves = append(moves, best)
+		}
+	} else {
+		for move, ending := range endings {
+			if ending != common.NoMove {
+				moves = append(moves, move)
+			}
+		}
+	}
+
+	for _, move := range moves {
+		y := board.MakeMove(move, player)
+		if moveWinner(p.solver, board, move, y, player) == common.Empty && !isATie(board) {
+			if _, visited := p.target.Get(board, depth); !visited {
+				p.target.Put(board, depth, endings[move])
+				p.visit(board)
+			}
+		}
+		board.UndoMove(move, y, player)
+	}
+}
+
+// strategyMove chooses a winning move, otherwise a tie or any known move, -1 if no ending is known.
+// When positions may repeat, the earliest proved win is chosen, so that the strategy doesn't go round in circles.
+func (p *strategyPruner) strategyMove(board *common.Board, endings []common.Player, player common.Player) int {
+	var orders []int
+	if prover, ok := p.solver.(common.IProofOrder); ok && countWinningMoves(endings, player) > 1 {
+		orders = prover.MovesProofOrder(board)
+	}
+	best := -1
+	for move, ending := range endings {
+		if ending == player && (best < 0 || orders != nil && orders[move] < orders[best]) {
+			best = move
+		}
+	}
+	if best >= 0 {
+		return best
+	}
+	for move, ending := range endings {
+		if ending == common.Empty {
+			return move
+		}
+	}
+	for move, ending := range endings {
+		if ending != common.NoMove {
+			return move
+		}
+	}
+	return -1
+}
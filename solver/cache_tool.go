@@ -0,0 +1,472 @@
+package solver
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+const (
+	TextDumpFormat  = "text"
+	CsvDumpFormat   = "csv"
+	JsonlDumpFormat = "jsonl"
+)
+
+// CacheTool inspects cache file of the board, running subcommand: stats, query, dump, diff, merge, verify, prune or import
+func CacheTool(boardOptions []common.Option, command []string, solverOptions ...common.SolverOption) error {
+	board := common.NewBoard(boardOptions...)
+	filename := CacheFilename(board)
+	if len(command) == 0 {
+		return errors.New("missing cache subcommand: stats, query, dump, diff, merge, verify, prune, import")
+	}
+
+	switch command[0] {
+	case "stats":
+		solver := CreateSolver(board, solverOptions...)
+		if err := common.LoadCacheFile(solver.Cache(), filename, log.Root()); err != nil {
+			return errors.Wrap(err, "loading cache")
+		}
+		depthBytes, err := common.CacheFileDepthSizes(filename)
+		if err != nil {
+			return errors.Wrap(err, "reading cache file")
+		}
+		printCacheStats(os.Stdout, cacheStats(solver.Cache().DepthCaches(), depthBytes))
+		return nil
+
+	case "query":
+		if len(command) < 2 {
+			return errors.New("missing moves to query, eg. query 0016")
+		}
+		board.ApplyMoves(command[1])
+		// only depths of the position and the following ones are read
+		depth := int(board.CountMoves())
+		depthCaches, err := common.ReadCacheFileDepths(filename, depth-1, depth, depth+1)
+		if err != nil {
+			return errors.Wrap(err, "reading cache file")
+		}
+		solver := CreateSolver(board, solverOptions...)
+		for d, depthCache := range depthCaches {
+			for key, ending := range depthCache {
+				solver.Cache().SetEntry(d, key, ending)
+			}
+		}
+		queryCache(os.Stdout, board, solver)
+		return nil
+
+	case "dump":
+		flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+		depth := flags.Int("depth", -1, "Dump entries of given depth only (-1 - all depths)")
+		format := flags.String("format", TextDumpFormat, "Output format: text, csv, jsonl")
+		if err := flags.Parse(command[1:]); err != nil {
+			return err
+		}
+		depths := []int{}
+		if *depth >= 0 {
+			depths = append(depths, *depth)
+		}
+		depthCaches, err := common.ReadCacheFileDepths(filename, depths...)
+		if err != nil {
+			return errors.Wrap(err, "reading cache file")
+		}
+		return dumpCache(os.Stdout, board, depthCaches, *format)
+
+	case "diff":
+		flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+		limit := flags.Int("limit", 10, "Maximum number of conflicting entries to show")
+		files, err := parseInterspersedFlags(flags, command[1:])
+		if err != nil {
+			return err
+		}
+		if len(files) == 1 {
+			files = []string{filename, files[0]}
+		}
+		if len(files) != 2 {
+			return errors.New("expected one or two cache files to compare")
+		}
+		depthCaches := [2][]map[uint64]common.Player{}
+		for i, file := range files {
+			solver := CreateSolver(board, solverOptions...)
+			if err := common.LoadCacheFile(solver.Cache(), file, log.Root()); err != nil {
+				return errors.Wrapf(err, "loading cache %s", file)
+			}
+			depthCaches[i] = solver.Cache().DepthCaches()
+		}
+		printCacheDiff(os.Stdout, board, diffCaches(depthCaches[0], depthCaches[1]), *limit)
+		return nil
+
+	case "merge":
+		flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+		output := flags.String("o", "", "Output cache file")
+		limit := flags.Int("limit", 10, "Maximum number of conflicting entries to show")
+		files, err := parseInterspersedFlags(flags, command[1:])
+		if err != nil {
+			return err
+		}
+		if len(files) < 2 || *output == "" {
+			return errors.New("expected at least two cache files to merge and output file, eg. merge a b -o c")
+		}
+		merged := CreateSolver(board, solverOptions...).Cache()
+		conflicts := []cacheConflict{}
+		for _, file := range files {
+			solver := CreateSolver(board, solverOptions...)
+			if err := common.LoadCacheFile(solver.Cache(), file, log.Root()); err != nil {
+				return errors.Wrapf(err, "loading cache %s", file)
+			}
+			conflicts = append(conflicts, mergeCaches(merged, solver.Cache().DepthCaches())...)
+		}
+		printCacheConflicts(os.Stdout, board, conflicts, *limit)
+		fmt.Printf("Merged %s entries, %s conflicts\n",
+			common.BigintSeparated(merged.Size()), common.BigintSeparated(uint64(len(conflicts))))
+		return errors.Wrap(SaveCacheFile(merged, *output), "saving merged cache")
+
+	case "verify":
+		flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+		samples := flags.Int("samples", 100, "Number of random entries to verify")
+		fresh := flags.Bool("fresh", false, "Solve sampled positions from scratch instead of relying on cached children")
+		if err := flags.Parse(command[1:]); err != nil {
+			return err
+		}
+		return verifyCache(board, filename, *samples, *fresh, solverOptions...)
+
+	case "prune":
+		flags := flag.NewFlagSet("prune", flag.ContinueOnError)
+		output := flags.String("o", "", "Output cache file")
+		sideName := flags.String("side", "auto", "Player following the strategy: A, B or auto (the winner)")
+		files, err := parseInterspersedFlags(flags, command[1:])
+		if err != nil {
+			return err
+		}
+		if *output == "" || len(files) > 1 {
+			return errors.New("expected output file and optional input file, eg. prune -o pruned.protobuf")
+		}
+		if len(files) == 1 {
+			filename = files[0]
+		}
+		side := common.Empty
+		switch *sideName {
+		case string(common.PlayerARune):
+			side = common.PlayerA
+		case string(common.PlayerBRune):
+			side = common.PlayerB
+		case "auto":
+		default:
+			return fmt.Errorf("invalid side: %s", *sideName)
+		}
+		solver := CreateSolver(board, solverOptions...)
+		if err := common.LoadCacheFile(solver.Cache(), filename, log.Root()); err != nil {
+			return errors.Wrap(err, "loading cache")
+		}
+		pruned := CreateSolver(board, solverOptions...).Cache()
+		side, err = pruneCache(board, solver, pruned, side)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Kept %s of %s entries for strategy of player %s\n", common.BigintSeparated(pruned.Size()),
+			common.BigintSeparated(solver.Cache().Size()), common.EndingName(side))
+		return errors.Wrap(common.SaveFullCacheFile(pruned, *output, CacheFileFormat, log.Root()), "saving pruned cache")
+
+	case "import":
+		flags := flag.NewFlagSet("import", flag.ContinueOnError)
+		output := flags.String("o", filename, "Output cache file")
+		name := flags.String("name", filepath.Base(filename), "Name of the cache file in the mirror")
+		sources, err := parseInterspersedFlags(flags, command[1:])
+		if err != nil {
+			return err
+		}
+		if len(sources) != 1 {
+			return errors.New("expected directory or URL of the mirror, eg. import http://localhost:8000/")
+		}
+		return importCache(sources[0], *name, *output)
+	}
+	return fmt.Errorf("unknown cache subcommand: %s", command[0])
+}
+
+type depthStats struct {
+	depth   int
+	entries int
+	winsA   int
+	winsB   int
+	ties    int
+	bytes   int
+}
+
+func cacheStats(depthCaches []map[uint64]common.Player, depthBytes []int) []depthStats {
+	stats := []depthStats{}
+	for d, depthCache := range depthCaches {
+		stat := depthStats{depth: d, entries: len(depthCache)}
+		if d < len(depthBytes) {
+			stat.bytes = depthBytes[d]
+		}
+		if stat.entries == 0 && stat.bytes == 0 {
+			continue
+		}
+		for _, ending := range depthCache {
+			switch ending {
+			case common.PlayerA:
+				stat.winsA++
+			case common.PlayerB:
+				stat.winsB++
+			case common.Empty:
+				stat.ties++
+			}
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+func printCacheStats(out io.Writer, stats []depthStats) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "depth\tentries\tA\tB\ttie\tbytes\t")
+	total := depthStats{}
+	for _, stat := range stats {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t\n", stat.depth,
+			common.BigintSeparated(uint64(stat.entries)),
+			endingRatio(stat.winsA, stat.entries), endingRatio(stat.winsB, stat.entries),
+			endingRatio(stat.ties, stat.entries), common.BigintSeparated(uint64(stat.bytes)))
+		total.entries += stat.entries
+		total.winsA += stat.winsA
+		total.winsB += stat.winsB
+		total.ties += stat.ties
+		total.bytes += stat.bytes
+	}
+	fmt.Fprintf(w, "total\t%s\t%s\t%s\t%s\t%s\t\n",
+		common.BigintSeparated(uint64(total.entries)),
+		endingRatio(total.winsA, total.entries), endingRatio(total.winsB, total.entries),
+		endingRatio(total.ties, total.entries), common.BigintSeparated(uint64(total.bytes)))
+	w.Flush()
+}
+
+func endingRatio(count int, entries int) string {
+	if entries == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(count)/float64(entries))
+}
+
+func queryCache(out io.Writer, board *common.Board, solver common.IMoveSolver) {
+	fmt.Fprintln(out, board.String())
+	player := board.NextPlayer()
+	depth := board.CountMoves()
+	if depth > 0 {
+		ending, ok := solver.Cache().Get(board, depth-1)
+		if ok {
+			fmt.Fprintf(out, "Cached ending: %s\n", common.EndingName(ending))
+		} else {
+			fmt.Fprintln(out, "Cached ending: none")
+		}
+	}
+	fmt.Fprintf(out, "Cached endings for player %s:\n", common.EndingName(player))
+	displays := []string{}
+	for _, ending := range getCachedEndings(board, solver) {
+		displays = append(displays, common.Display.ShortEnding(ending))
+	}
+	fmt.Fprintln(out, "| "+strings.Join(displays, " ")+" |")
+}
+
+type cacheEntry struct {
+	Depth  int    `json:"depth"`
+	Key    uint64 `json:"key"`
+	Ending string `json:"ending"`
+	Next   string `json:"next"`
+	Board  string `json:"board"`
+
+	decoded *common.Board
+}
+
+func sortedCacheEntries(board *common.Board, depth int, depthCache map[uint64]common.Player) []cacheEntry {
+	keys := make([]uint64, 0, len(depthCache))
+	for key := range depthCache {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	entries := make([]cacheEntry, len(keys))
+	for i, key := range keys {
+		decoded, next := common.DecodeCacheKey(board, key)
+		entries[i] = cacheEntry{
+			Depth:  depth,
+			Key:    key,
+			Ending: common.EndingName(depthCache[key]),
+			Next:   common.EndingName(next),
+			Board:  decoded.LayoutString(),
+
+			decoded: decoded,
+		}
+	}
+	return entries
+}
+
+func dumpCache(out io.Writer, board *common.Board, depthCaches []map[uint64]common.Player, format string) error {
+	var csvWriter *csv.Writer
+	switch format {
+	case TextDumpFormat, JsonlDumpFormat:
+	case CsvDumpFormat:
+		csvWriter = csv.NewWriter(out)
+		csvWriter.Write([]string{"depth", "key", "ending", "next", "board"})
+	default:
+		return fmt.Errorf("unknown dump format: %s", format)
+	}
+	encoder := json.NewEncoder(out)
+
+	for d, depthCache := range depthCaches {
+		for _, entry := range sortedCacheEntries(board, d, depthCache) {
+			switch format {
+			case TextDumpFormat:
+				fmt.Fprintf(out, "depth: %d, key: %d, ending: %s, next: %s\n",
+					entry.Depth, entry.Key, entry.Ending, entry.Next)
+				fmt.Fprintln(out, entry.decoded.String())
+			case CsvDumpFormat:
+				csvWriter.Write([]string{fmt.Sprint(entry.Depth), fmt.Sprint(entry.Key),
+					entry.Ending, entry.Next, entry.Board})
+			case JsonlDumpFormat:
+				if err := encoder.Encode(entry); err != nil {
+					return errors.Wrap(err, "encoding entry")
+				}
+			}
+		}
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	return nil
+}
+
+type depthDiff struct {
+	depth     int
+	onlyLeft  int
+	onlyRight int
+	same      int
+	conflicts []cacheConflict
+}
+
+// cacheConflict is a position having different endings in compared caches
+type cacheConflict struct {
+	depth int
+	key   uint64
+	left  common.Player
+	right common.Player
+}
+
+// diffCaches compares entries of two caches depth by depth
+func diffCaches(left, right []map[uint64]common.Player) []depthDiff {
+	depths := len(left)
+	if len(right) > depths {
+		depths = len(right)
+	}
+	diffs := []depthDiff{}
+	for d := 0; d < depths; d++ {
+		var leftCache, rightCache map[uint64]common.Player
+		if d < len(left) {
+			leftCache = left[d]
+		}
+		if d < len(right) {
+			rightCache = right[d]
+		}
+		diff := depthDiff{depth: d}
+		for key, leftEnding := range leftCache {
+			rightEnding, ok := rightCache[key]
+			if !ok {
+				diff.onlyLeft++
+			} else if rightEnding != leftEnding {
+				diff.conflicts = append(diff.conflicts, cacheConflict{depth: d, key: key, left: leftEnding, right: rightEnding})
+			} else {
+				diff.same++
+			}
+		}
+		for key := range rightCache {
+			if _, ok := leftCache[key]; !ok {
+				diff.onlyRight++
+			}
+		}
+		if diff.onlyLeft+diff.onlyRight+diff.same+len(diff.conflicts) == 0 {
+			continue
+		}
+		sort.Slice(diff.conflicts, func(i, j int) bool { return diff.conflicts[i].key < diff.conflicts[j].key })
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+func printCacheDiff(out io.Writer, board *common.Board, diffs []depthDiff, limit int) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "depth\tsame\tonly left\tonly right\tconflicts\t")
+	for _, diff := range diffs {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t\n", diff.depth,
+			common.BigintSeparated(uint64(diff.same)), common.BigintSeparated(uint64(diff.onlyLeft)),
+			common.BigintSeparated(uint64(diff.onlyRight)), common.BigintSeparated(uint64(len(diff.conflicts))))
+	}
+	w.Flush()
+
+	conflicts := []cacheConflict{}
+	for _, diff := range diffs {
+		conflicts = append(conflicts, diff.conflicts...)
+	}
+	printCacheConflicts(out, board, conflicts, limit)
+}
+
+func printCacheConflicts(out io.Writer, board *common.Board, conflicts []cacheConflict, limit int) {
+	for i, conflict := range conflicts {
+		if i >= limit {
+			fmt.Fprintf(out, "... and %d more conflicts\n", len(conflicts)-limit)
+			return
+		}
+		decoded, _ := common.DecodeCacheKey(board, conflict.key)
+		fmt.Fprintf(out, "conflict at depth %d: %s, left: %s, right: %s\n", conflict.depth,
+			decoded.LayoutString(), common.EndingName(conflict.left), common.EndingName(conflict.right))
+	}
+}
+
+// mergeCaches adds entries of the source cache to the target one, keeping target endings on conflicts
+func mergeCaches(target common.ICache, source []map[uint64]common.Player) []cacheConflict {
+	conflicts := []cacheConflict{}
+	targetCaches := target.DepthCaches()
+	for d, depthCache := range source {
+		if d >= len(targetCaches) {
+			break
+		}
+		keys := make([]uint64, 0, len(depthCache))
+		for key := range depthCache {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		for _, key := range keys {
+			ending := depthCache[key]
+			targetEnding, ok := targetCaches[d][key]
+			if !ok {
+				target.SetEntry(d, key, ending)
+			} else if targetEnding != ending {
+				conflicts = append(conflicts, cacheConflict{depth: d, key: key, left: targetEnding, right: ending})
+			}
+		}
+	}
+	return conflicts
+}
+
+// parseInterspersedFlags parses flags placed anywhere between positional arguments, returning the latter
+func parseInterspersedFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
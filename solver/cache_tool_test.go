@@ -0,0 +1,285 @@
+package solver
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/igrek51/connect4solver/solver/common"
+	"github.com/igrek51/connect4solver/solver/generic_solver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheFileDepths(t *testing.T) {
+	board := NewBoard(WithSize(4, 4))
+	cache := generic_solver.NewEndingCache(4, 4, DefaultCacheSizeLimit, DiscardLogger())
+	cache.Put(NewBoard(WithSize(4, 4)).ApplyMoves("1"), 0, Empty)
+	cache.Put(NewBoard(WithSize(4, 4)).ApplyMoves("12"), 1, PlayerA)
+	cache.Put(NewBoard(WithSize(4, 4)).ApplyMoves("11"), 1, PlayerB)
+	filename := filepath.Join(t.TempDir(), "cache.protobuf")
+	assert.NoError(t, SaveCacheFile(cache, filename))
+
+	depthCaches, err := ReadCacheFileDepths(filename, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, depthCaches[0])
+	assert.Len(t, depthCaches[1], 2)
+
+	sizes, err := CacheFileDepthSizes(filename)
+	assert.NoError(t, err)
+	assert.Len(t, sizes, len(depthCaches))
+
+	loaded := generic_solver.NewEndingCache(4, 4, DefaultCacheSizeLimit, DiscardLogger())
+	assert.NoError(t, LoadCacheFile(loaded, filename, DiscardLogger()))
+	stats := cacheStats(loaded.DepthCaches(), sizes)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, depthStats{depth: 1, entries: 2, winsA: 1, winsB: 1, bytes: sizes[1]}, stats[1])
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, dumpCache(out, board, depthCaches, CsvDumpFormat))
+	assert.Equal(t, `depth,key,ending,next,board
+1,16974337,A,A,..../..../..../.AB.
+1,17170689,B,A,..../..../..B./..A.
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, dumpCache(out, board, depthCaches, JsonlDumpFormat))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, `{"depth":1,"key":16974337,"ending":"A","next":"A","board":"..../..../..../.AB."}`, lines[0])
+
+	assert.Error(t, dumpCache(out, board, depthCaches, "xml"))
+}
+
+func TestDecodeCacheKeyPopOut(t *testing.T) {
+	board := NewBoard(WithSize(3, 3), WithVariant(PopOutVariant))
+	decoded, next := DecodeCacheKey(board, 0b10<<8|0b11<<16|1|1<<63)
+	assert.Equal(t, ".../.../.AB", decoded.LayoutString())
+	assert.Equal(t, PlayerB, next)
+}
+
+func TestDiffCaches(t *testing.T) {
+	left := []map[uint64]Player{
+		{1: PlayerA, 2: Empty},
+		{3: PlayerB, 4: PlayerA},
+	}
+	right := []map[uint64]Player{
+		{1: PlayerA},
+		{3: Empty, 4: PlayerA, 5: Empty},
+		{6: PlayerB},
+	}
+
+	diffs := diffCaches(left, right)
+
+	assert.Equal(t, []depthDiff{
+		{depth: 0, same: 1, onlyLeft: 1},
+		{depth: 1, same: 1, onlyRight: 1, conflicts: []cacheConflict{{depth: 1, key: 3, left: PlayerB, right: Empty}}},
+		{depth: 2, onlyRight: 1},
+	}, diffs)
+}
+
+func TestMergeCaches(t *testing.T) {
+	merged := generic_solver.NewEndingCache(4, 4, DefaultCacheSizeLimit, DiscardLogger())
+	merged.SetEntry(0, 1, PlayerA)
+
+	conflicts := mergeCaches(merged, []map[uint64]Player{
+		{1: PlayerB, 2: Empty},
+		{3: PlayerB},
+	})
+
+	assert.Equal(t, []cacheConflict{{depth: 0, key: 1, left: PlayerA, right: PlayerB}}, conflicts)
+	assert.EqualValues(t, 3, merged.Size())
+	assert.Equal(t, PlayerA, merged.DepthCaches()[0][1])
+	assert.Equal(t, PlayerB, merged.DepthCaches()[1][3])
+}
+
+func TestVerifyCacheEntry(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3))
+	solver := CreateSolver(board)
+	solver.MovesEndings(board)
+	depthCaches := solver.Cache().DepthCaches()
+	sampled := sampleCacheEntries(depthCaches, 20, rand.New(rand.NewSource(1)))
+	assert.Len(t, sampled, 20)
+
+	for _, sample := range sampled {
+		assert.NoError(t, verifyCacheEntry(board, CreateSolver(board), sample))
+	}
+
+	corrupted := sampled[0]
+	corrupted.ending = Empty
+	if sampled[0].ending == Empty {
+		corrupted.ending = PlayerA
+	}
+	assert.Error(t, verifyCacheEntry(board, CreateSolver(board), corrupted))
+	corrupted = sampled[0]
+	corrupted.depth++
+	assert.Error(t, verifyCacheEntry(board, CreateSolver(board), corrupted))
+}
+
+func TestParseInterspersedFlags(t *testing.T) {
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	output := flags.String("o", "", "")
+	files, err := parseInterspersedFlags(flags, []string{"a", "b", "-o", "c", "d"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "d"}, files)
+	assert.Equal(t, "c", *output)
+}
+
+func TestPruneCacheKeepsWinningStrategy(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3))
+	solver := CreateSolver(board)
+	solver.MovesEndings(board)
+	pruned := CreateSolver(board)
+
+	side, err := pruneCache(board, solver, pruned.Cache(), Empty)
+
+	assert.NoError(t, err)
+	assert.Equal(t, PlayerA, side)
+	assert.Less(t, pruned.Cache().Size()*4, solver.Cache().Size())
+
+	random := rand.New(rand.NewSource(1))
+	for game := 0; game < 20; game++ {
+		board.Clear()
+		for {
+			player := board.NextPlayer()
+			var move int
+			if player == side {
+				endings := getCachedWinningEndings(board, pruned)
+				assert.NotNil(t, endings)
+				move = findBestMove(board, estimateMoveScores(pruned, endings, player, board, false))
+			} else {
+				move = random.Intn(board.W)
+				if !board.CanMakeMove(move) {
+					continue
+				}
+			}
+			y := board.MakeMove(move, player)
+			if winner := moveWinner(pruned, board, move, y, player); winner != Empty {
+				assert.Equal(t, side, winner)
+				break
+			}
+			assert.False(t, isATie(board))
+		}
+	}
+}
+
+func TestPrunedCacheFilePlaysPerfectly(t *testing.T) {
+	for name, boardOptions := range map[string][]Option{
+		"4x4":    {WithSize(4, 4), WithWinStreak(3)},
+		"PopOut": {WithSize(3, 3), WithWinStreak(3), WithVariant(PopOutVariant)},
+	} {
+		board := NewBoard(boardOptions...)
+		solver := CreateSolver(board)
+		// PopOut solver caches only the requested positions
+		cacheReachableEndings(board, solver, map[treePosition]bool{})
+		dir := t.TempDir()
+		input := filepath.Join(dir, "cache.protobuf")
+		output := filepath.Join(dir, "pruned.protobuf")
+		assert.NoError(t, SaveFullCacheFile(solver.Cache(), input, CacheFileFormat, DiscardLogger()), name)
+
+		assert.NoError(t, CacheTool(boardOptions, []string{"prune", input, "-o", output}), name)
+		pruned := CreateSolver(board)
+		assert.NoError(t, LoadCacheFile(pruned.Cache(), output, DiscardLogger()), name)
+		inMemory := CreateSolver(board)
+		side, err := pruneCache(board, solver, inMemory.Cache(), Empty)
+		assert.NoError(t, err, name)
+		assert.Equal(t, inMemory.Cache().Size(), pruned.Cache().Size(), "all depths of pruned cache are saved: %s", name)
+
+		// automatic player makes only cached winning moves, whatever the opponent does
+		assertCachedWinningStrategy(t, pruned, NewBoard(boardOptions...), side, map[treePosition]bool{})
+	}
+}
+
+// cacheReachableEndings solves all positions reachable from the board, so that the cache contains the whole game
+func cacheReachableEndings(board *Board, solver IMoveSolver, visited map[treePosition]bool) {
+	visited[newTreePosition(board)] = true
+	player := board.NextPlayer()
+	solver.MovesEndings(board)
+	for move := 0; move < board.MoveSlots(); move++ {
+		if !board.CanPlay(move, player) {
+			continue
+		}
+		y := board.MakeMove(move, player)
+		if moveWinner(solver, board, move, y, player) == Empty && !isATie(board) && !visited[newTreePosition(board)] {
+			cacheReachableEndings(board, solver, visited)
+		}
+		board.UndoMove(move, y, player)
+	}
+}
+
+// assertCachedWinningStrategy plays cached winning moves of the side against every possible reply of the opponent,
+// the strategy mustn't come back to any position on the way
+func assertCachedWinningStrategy(t *testing.T, solver IMoveSolver, board *Board, side Player, path map[treePosition]bool) {
+	position := newTreePosition(board)
+	if !assert.False(t, path[position], "position repeated after %d moves", len(path)) {
+		return
+	}
+	path[position] = true
+	defer delete(path, position)
+
+	player := board.NextPlayer()
+	moves := []int{}
+	if player == side {
+		endings := getCachedWinningEndings(board, solver)
+		if !assert.NotNil(t, endings, "no cached winning move after %d moves", len(path)) {
+			return
+		}
+		moves = append(moves, findBestMove(board, estimateMoveScores(solver, endings, player, board, false)))
+	} else {
+		for move := 0; move < board.MoveSlots(); move++ {
+			if board.CanPlay(move, player) {
+				moves = append(moves, move)
+			}
+		}
+	}
+	for _, move := range moves {
+		y := board.MakeMove(move, player)
+		if winner := moveWinner(solver, board, move, y, player); winner != Empty {
+			assert.Equal(t, side, winner)
+		} else if assert.False(t, isATie(board)) {
+			assertCachedWinningStrategy(t, solver, board, side, path)
+		}
+		board.UndoMove(move, y, player)
+	}
+}
+
+func TestCacheFileFormats(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3))
+	solver := CreateSolver(board)
+	solver.MovesEndings(board)
+	defer func(format CacheFormat) { CacheFileFormat = format }(CacheFileFormat)
+
+	sizes := map[CacheFormat]int{}
+	for _, format := range []CacheFormat{ProtobufCacheFormat, CompactCacheFormat, CompactGzipCacheFormat} {
+		CacheFileFormat = format
+		filename := filepath.Join(t.TempDir(), "cache")
+		assert.NoError(t, SaveCacheFile(solver.Cache(), filename))
+		info, err := os.Stat(filename)
+		assert.NoError(t, err)
+		sizes[format] = int(info.Size())
+
+		loaded := CreateSolver(board).Cache()
+		assert.NoError(t, LoadCacheFile(loaded, filename, DiscardLogger()))
+		maxDepth := int(solver.Cache().MaxCachedDepth() / 2)
+		for d, depthCache := range solver.Cache().DepthCaches() {
+			if d <= maxDepth {
+				assert.Equal(t, depthCache, loaded.DepthCaches()[d], "format %s, depth %d", format, d)
+			}
+		}
+
+		depthCaches, err := ReadCacheFileDepths(filename, 3)
+		assert.NoError(t, err)
+		assert.Nil(t, depthCaches[2])
+		assert.Equal(t, solver.Cache().DepthCaches()[3], depthCaches[3])
+	}
+	assert.Less(t, sizes[CompactCacheFormat], sizes[ProtobufCacheFormat])
+	assert.Less(t, sizes[CompactGzipCacheFormat], sizes[CompactCacheFormat])
+
+	filename := filepath.Join(t.TempDir(), "cache")
+	assert.NoError(t, ioutil.WriteFile(filename, []byte("C4CC\x01\x00\x05\x09"), 0644))
+	assert.Error(t, LoadCacheFile(CreateSolver(board).Cache(), filename, DiscardLogger()))
+}
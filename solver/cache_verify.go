@@ -0,0 +1,119 @@
+package solver
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+type cacheSample struct {
+	depth  int
+	key    uint64
+	ending common.Player
+}
+
+// verifyCache re-derives endings of randomly sampled cache entries and checks if they match the cached ones
+func verifyCache(
+	board *common.Board,
+	filename string,
+	samples int,
+	fresh bool,
+	solverOptions ...common.SolverOption,
+) error {
+	if board.Variant == common.PopOutVariant {
+		return fmt.Errorf("verifying isn't supported in %s variant, its positions are solved all at once", board.Variant)
+	}
+	solver := CreateSolver(board, solverOptions...)
+	if err := common.LoadCacheFile(solver.Cache(), filename, log.Root()); err != nil {
+		return errors.Wrap(err, "loading cache")
+	}
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	sampled := sampleCacheEntries(solver.Cache().DepthCaches(), samples, random)
+
+	inconsistent := 0
+	for i, sample := range sampled {
+		if fresh {
+			solver = CreateSolver(board, solverOptions...)
+		}
+		err := verifyCacheEntry(board, solver, sample)
+		if err != nil {
+			inconsistent++
+			decoded, _ := common.DecodeCacheKey(board, sample.key)
+			log.Error("Inconsistent cache entry", log.Ctx{
+				"depth": sample.depth,
+				"key":   sample.key,
+				"board": decoded.LayoutString(),
+				"error": err,
+			})
+		}
+		log.Debug("Entry verified", log.Ctx{"sample": i + 1, "samples": len(sampled)})
+	}
+
+	log.Info("Cache verified", log.Ctx{
+		"samples":      len(sampled),
+		"inconsistent": inconsistent,
+	})
+	if inconsistent > 0 {
+		return fmt.Errorf("found %d inconsistent entries out of %d", inconsistent, len(sampled))
+	}
+	return nil
+}
+
+// sampleCacheEntries picks random entries of all depths using reservoir sampling
+func sampleCacheEntries(depthCaches []map[uint64]common.Player, samples int, random *rand.Rand) []cacheSample {
+	sampled := []cacheSample{}
+	seen := 0
+	for d, depthCache := range depthCaches {
+		for key, ending := range depthCache {
+			seen++
+			if len(sampled) < samples {
+				sampled = append(sampled, cacheSample{depth: d, key: key, ending: ending})
+			} else if i := random.Intn(seen); i < samples {
+				sampled[i] = cacheSample{depth: d, key: key, ending: ending}
+			}
+		}
+	}
+	return sampled
+}
+
+// verifyCacheEntry solves moves of the cached position (making use of cached child entries)
+// and checks if the best of them leads to the cached ending
+func verifyCacheEntry(board *common.Board, solver common.IMoveSolver, sample cacheSample) error {
+	decoded, nextPlayer := common.DecodeCacheKey(board, sample.key)
+	// entry of depth d keeps ending of the position after the move d
+	if int(decoded.CountMoves()) != sample.depth+1 {
+		return fmt.Errorf("position has %d moves, expected %d at this depth", decoded.CountMoves(), sample.depth+1)
+	}
+	endings := solver.MovesEndings(decoded)
+	if endings == nil {
+		return errors.New("solving interrupted")
+	}
+	derived := bestEnding(endings, nextPlayer)
+	if derived != sample.ending {
+		return fmt.Errorf("cached ending %s, but moves lead to %s",
+			common.EndingName(sample.ending), common.EndingName(derived))
+	}
+	return nil
+}
+
+// bestEnding chooses the best of move endings from the player's perspective: win, then tie, then lose
+func bestEnding(endings []common.Player, player common.Player) common.Player {
+	ties := 0
+	for _, ending := range endings {
+		if ending == player {
+			return player
+		}
+		if ending == common.Empty {
+			ties++
+		}
+	}
+	if ties > 0 {
+		return common.Empty
+	}
+	return common.OppositePlayer(player)
+}
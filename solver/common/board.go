@@ -17,7 +17,16 @@ type Board struct {
 	W         int
 	H         int
 	WinStreak int
+	Variant   Variant
 	State     BoardKey
+	Pops      [2]int // number of tokens popped out by each player
+	Turns     TurnSchedule
+	// Heights of columns, they are all equal to H on a rectangular board
+	Heights [7]int
+	// Neutral marks cells taken by neutral tokens, they're stored in State as regular tokens of player A
+	Neutral BoardKey
+
+	customHeights bool
 }
 
 type BoardKey [7]uint64
@@ -32,24 +41,48 @@ func init() {
 }
 
 func NewBoard(options ...Option) *Board {
+	b, err := BuildBoard(options...)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// BuildBoard creates an empty board, returning error on invalid options
+func BuildBoard(options ...Option) (*Board, error) {
 	// set defaults
 	b := &Board{
 		W:         4,
 		H:         4,
 		WinStreak: 4,
+		Variant:   StandardVariant,
 	}
 
 	// apply options
 	for _, opt := range options {
 		err := opt(b)
 		if err != nil {
-			panic(errors.Wrap(err, "applying option"))
+			return nil, errors.Wrap(err, "applying option")
 		}
 	}
+	if b.W < 1 || b.W > len(b.State) {
+		return nil, fmt.Errorf("board width should be in range [1-%d], got %d", len(b.State), b.W)
+	}
+	if b.H < 1 || b.H > 6 {
+		return nil, fmt.Errorf("board height should be in range [1-6], got %d", b.H)
+	}
+	if b.WinStreak < 1 {
+		return nil, fmt.Errorf("win streak should be positive, got %d", b.WinStreak)
+	}
 
+	if !b.customHeights {
+		for x := 0; x < b.W; x++ {
+			b.Heights[x] = b.H
+		}
+	}
 	b.State = [7]uint64{}
 	b.Clear()
-	return b
+	return b, nil
 }
 
 type Option func(*Board) error
@@ -74,6 +107,9 @@ func (b *Board) GetCell(x int, y int) Player {
 	if y >= b.StackSize(x) {
 		return Empty
 	}
+	if b.Neutral[x]>>y&0b1 != 0 {
+		return Neutral
+	}
 	return Player((b.State[x] >> y) & 0b1)
 }
 
@@ -89,51 +125,37 @@ func (b *Board) Throw(x int, player Player) int {
 func (b *Board) Revert(x int, y int) {
 	// colsize = y + 1
 	b.State[x] = (b.State[x] & ^(1 << (y + 1))) | (1 << y)
+	b.Neutral[x] &= ^(1 << y)
+}
+
+// Pop removes token from the bottom of the column
+func (b *Board) Pop(x int, player Player) {
+	b.State[x] >>= 1
+	b.Neutral[x] >>= 1
+	b.Pops[player]++
+}
+
+// Unpop puts back popped token to the bottom of the column
+func (b *Board) Unpop(x int, player Player) {
+	b.State[x] = b.State[x]<<1 | uint64(player)
+	b.Neutral[x] <<= 1
+	b.Pops[player]--
 }
 
 func (b *Board) StackSize(x int) int {
 	return StackSizeLookup[b.State[x]]
 }
 
+// String renders the board with the Display renderer
 func (b *Board) String() string {
-	lines := []string{}
-	var line string
-
-	line = "+-"
-	for i := 0; i < b.W; i++ {
-		line += "--"
-	}
-	line += "+"
-	lines = append(lines, line)
-
-	for y := b.H - 1; y >= 0; y-- {
-		rowCells := []string{}
-		for x := 0; x < b.W; x++ {
-			cell := b.GetCell(x, y)
-			rowCells = append(rowCells, PlayerDisplays[cell])
-		}
-		line = "| " + strings.Join(rowCells, " ") + " |"
-		lines = append(lines, line)
-	}
-
-	line = "+-"
-	for i := 0; i < b.W; i++ {
-		line += "--"
-	}
-	line += "+"
-	lines = append(lines, line)
-
-	coordinates := []string{}
-	for x := 0; x < b.W; x++ {
-		coordinates = append(coordinates, fmt.Sprint(x))
-	}
-	line = "| " + strings.Join(coordinates, " ") + " |"
-	lines = append(lines, line)
-
-	return strings.Join(lines, "\n")
+	return Display.Board(b)
 }
 
 func (b *Board) NextPlayer() Player {
+	if !b.Turns.IsAlternating() {
+		// each pop removes a token, but takes a move
+		return b.Turns.PlayerAtPly(int(b.CountMoves()) + 2*(b.Pops[PlayerA]+b.Pops[PlayerB]))
+	}
 	tokensA := 0
 	tokensB := 0
 	for x := 0; x < b.W; x++ {
@@ -146,6 +168,9 @@ func (b *Board) NextPlayer() Player {
 			}
 		}
 	}
+	// each pop removes own token and takes a turn
+	tokensA += 2 * b.Pops[PlayerA]
+	tokensB += 2 * b.Pops[PlayerB]
 	if tokensA > tokensB {
 		return PlayerB
 	} else {
@@ -167,13 +192,72 @@ func (b *Board) CountMoves() uint {
 }
 
 func (b *Board) CanMakeMove(x int) bool {
-	return b.State[x]>>b.H == 0
+	return b.State[x]>>b.Heights[x] == 0
 }
 
+// Clear removes all tokens, except neutral ones lying on the bottom, as they're a part of the board shape
 func (b *Board) Clear() {
 	for x := 0; x < b.W; x++ {
-		b.State[x] = 0b1
+		blocked := bits.TrailingZeros64(^b.Neutral[x])
+		b.State[x] = 1 << blocked
+		b.Neutral[x] = 1<<blocked - 1
+	}
+	b.Pops = [2]int{}
+}
+
+// MoveSlots is the number of possible move kinds: drops to each column followed by pops from each column
+func (b *Board) MoveSlots() int {
+	if b.Variant == PopOutVariant {
+		return 2 * b.W
 	}
+	return b.W
+}
+
+func (b *Board) IsPopMove(move int) bool {
+	return move >= b.W
+}
+
+// PopMove returns move number of popping out a token from the column
+func (b *Board) PopMove(x int) int {
+	return b.W + x
+}
+
+func (b *Board) CanPop(x int, player Player) bool {
+	return b.Variant == PopOutVariant && b.StackSize(x) > 0 && b.GetCell(x, 0) == player
+}
+
+// CanPlay checks if player can make a move of any kind
+func (b *Board) CanPlay(move int, player Player) bool {
+	if b.IsPopMove(move) {
+		return b.CanPop(move-b.W, player)
+	}
+	return b.CanMakeMove(move)
+}
+
+// MakeMove makes a move of any kind, returns y coordinate of dropped token
+func (b *Board) MakeMove(move int, player Player) int {
+	if b.IsPopMove(move) {
+		b.Pop(move-b.W, player)
+		return 0
+	}
+	return b.Throw(move, player)
+}
+
+// UndoMove reverts a move done by MakeMove
+func (b *Board) UndoMove(move int, y int, player Player) {
+	if b.IsPopMove(move) {
+		b.Unpop(move-b.W, player)
+	} else {
+		b.Revert(move, y)
+	}
+}
+
+// MoveString renders move number, prefixing pops with "p", eg. "3" or "p3"
+func (b *Board) MoveString(move int) string {
+	if b.IsPopMove(move) {
+		return fmt.Sprintf("p%d", move-b.W)
+	}
+	return fmt.Sprint(move)
 }
 
 func (b *Board) Clone() *Board {
@@ -186,15 +270,29 @@ func (b *Board) Clone() *Board {
 		W:         b.W,
 		H:         b.H,
 		WinStreak: b.WinStreak,
+		Variant:   b.Variant,
 		State:     state,
+		Pops:      b.Pops,
+		Turns:     b.Turns,
+		Heights:   b.Heights,
+		Neutral:   b.Neutral,
+
+		customHeights: b.customHeights,
 	}
 }
 
+// ApplyMoves makes consecutive moves given as column numbers, eg. "0016",
+// pops in PopOut variant are prefixed with "p", eg. "0p0"
 func (b *Board) ApplyMoves(startWithMoves string) *Board {
 	if startWithMoves == "" {
 		return b
 	}
+	pop := false
 	for idx, moveStr := range startWithMoves {
+		if moveStr == 'p' && b.Variant == PopOutVariant {
+			pop = true
+			continue
+		}
 		move, err := strconv.Atoi(string(moveStr))
 		if err != nil {
 			log.Error("Invalid number", log.Ctx{"error": err, "index": idx, "move": moveStr})
@@ -204,28 +302,58 @@ func (b *Board) ApplyMoves(startWithMoves string) *Board {
 			log.Error("Move number is out of range", log.Ctx{"move": move, "index": idx})
 			return b
 		}
-		if !b.CanMakeMove(move) {
+		player := b.NextPlayer()
+		if pop {
+			pop = false
+			move = b.PopMove(move)
+			if !b.CanPlay(move, player) {
+				log.Error("Can't pop out from the column", log.Ctx{"move": move, "index": idx})
+				return b
+			}
+		} else if !b.CanMakeMove(move) {
 			log.Error("Column is already full", log.Ctx{"move": move, "index": idx})
 			return b
 		}
-		player := b.NextPlayer()
-		b.Throw(move, player)
+		b.MakeMove(move, player)
 	}
 	return b
 }
 
-func ParseBoard(txt string, options ...Option) *Board {
-	txt = strings.TrimSpace(txt)
-	lines := strings.Split(txt, "\n")
-	lines = ReverseLines(lines)
-	for i, line := range lines {
-		line = strings.ReplaceAll(line, " ", "")
-		lines[i] = strings.ReplaceAll(line, "\t", "")
+// ParseMove reads move number from user input, eg. "3" or "p3" for popping out
+func (b *Board) ParseMove(input string) (int, error) {
+	input = strings.TrimSpace(input)
+	pop := strings.HasPrefix(input, "p")
+	if pop {
+		if b.Variant != PopOutVariant {
+			return 0, errors.New("popping out is allowed only in PopOut variant")
+		}
+		input = strings.TrimPrefix(input, "p")
+	}
+	move, err := strconv.Atoi(input)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid number")
+	}
+	if move < 0 || move >= b.W {
+		return 0, errors.New("move number is out of range")
+	}
+	if pop {
+		return b.PopMove(move), nil
 	}
+	return move, nil
+}
+
+// ParseBoard reads board from rows of cells (top row first): "A", "B" - players' tokens, "." - empty cell,
+// "X" - neutral token, "#" - cell outside of the board shape (only above the column)
+func ParseBoard(txt string, options ...Option) *Board {
+	lines := parseLayoutLines(txt)
 	h := len(lines)
 	w := len(lines[0])
 
-	newOptions := append([]Option{WithSize(w, h)}, options...)
+	newOptions := []Option{WithSize(w, h)}
+	if heights, shaped := layoutHeights(lines); shaped {
+		newOptions = append(newOptions, WithColumnHeights(heights...))
+	}
+	newOptions = append(newOptions, options...)
 	board := NewBoard(newOptions...)
 	for _, line := range lines {
 		for x, cell := range line {
@@ -233,6 +361,8 @@ func ParseBoard(txt string, options ...Option) *Board {
 				board.Throw(x, PlayerA)
 			} else if cell == PlayerBRune {
 				board.Throw(x, PlayerB)
+			} else if cell == NeutralRune {
+				board.ThrowNeutral(x)
 			}
 		}
 	}
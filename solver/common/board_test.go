@@ -151,3 +151,105 @@ func TestApplyMoves(t *testing.T) {
 | 0 1 2 3 4 5 6 |
 `)
 }
+
+func TestPopOut(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithVariant(PopOutVariant))
+	board.ApplyMoves("0011p0")
+	AssertEqualTrimmed(t, board.String(), `
++---------+
+| . . . . |
+| . . . . |
+| . B . . |
+| B A . . |
++---------+
+| 0 1 2 3 |
+`)
+	assert.Equal(t, PlayerB, board.NextPlayer())
+	assert.Equal(t, 8, board.MoveSlots())
+	assert.True(t, board.CanPlay(board.PopMove(0), PlayerB))
+	assert.True(t, board.CanPlay(board.PopMove(1), PlayerA))
+	assert.False(t, board.CanPlay(board.PopMove(1), PlayerB))
+	assert.False(t, board.CanPlay(board.PopMove(3), PlayerB))
+
+	board.UndoMove(board.PopMove(0), 0, PlayerA)
+	assert.Equal(t, PlayerA, board.GetCell(0, 0))
+	assert.Equal(t, PlayerA, board.NextPlayer())
+
+	move, err := board.ParseMove("p2")
+	assert.NoError(t, err)
+	assert.Equal(t, board.PopMove(2), move)
+	assert.Equal(t, "p2", board.MoveString(move))
+	_, err = NewBoard(WithSize(4, 4)).ParseMove("p2")
+	assert.Error(t, err)
+}
+
+func TestTurnSchedule(t *testing.T) {
+	schedule, err := ParseTurnSchedule("1, 2")
+	assert.NoError(t, err)
+	assert.Equal(t, TurnSchedule{1, 2}, schedule)
+	assert.False(t, schedule.IsAlternating())
+	assert.Equal(t, "1-2", schedule.String())
+	assert.Equal(t, []Player{PlayerA, PlayerB, PlayerB, PlayerA, PlayerA, PlayerB, PlayerB}, schedule.PlayersByPly(7))
+	assert.True(t, TurnSchedule(nil).IsAlternating())
+	assert.Equal(t, []Player{PlayerA, PlayerB, PlayerA}, TurnSchedule(nil).PlayersByPly(3))
+
+	_, err = ParseTurnSchedule("1,0")
+	assert.Error(t, err)
+	_, err = ParseTurnSchedule("1,x")
+	assert.Error(t, err)
+
+	board := NewBoard(WithSize(4, 4), WithTurnSchedule(schedule))
+	board.ApplyMoves("10")
+	assert.Equal(t, PlayerB, board.NextPlayer())
+	board.ApplyMoves("3")
+	assert.Equal(t, PlayerA, board.NextPlayer())
+	assert.Equal(t, PlayerB, board.GetCell(3, 0))
+}
+
+func TestParseShapedBoard(t *testing.T) {
+	board := ParseBoard(`
+	#..#
+	.BX.
+	XAA.
+	`)
+	assert.Equal(t, [7]int{2, 3, 3, 2}, board.Heights)
+	assert.Equal(t, Neutral, board.GetCell(0, 0))
+	assert.Equal(t, Neutral, board.GetCell(2, 1))
+	assert.Equal(t, PlayerA, board.GetCell(2, 0))
+	assert.Equal(t, PlayerB, board.GetCell(1, 1))
+	assert.EqualValues(t, 3, board.CountMoves())
+	assert.Equal(t, PlayerB, board.NextPlayer())
+	assert.Equal(t, 8, board.PlayableCells())
+	assert.False(t, board.IsRectangular())
+	assert.False(t, board.IsMirrorSymmetric())
+	assert.True(t, board.CanMakeMove(0))
+	assert.False(t, NewBoard(WithColumnHeights(2, 0)).CanMakeMove(1))
+	AssertEqualTrimmed(t, board.String(), `
++---------+
+| # . . # |
+| . B X . |
+| X A A . |
++---------+
+| 0 1 2 3 |
+`)
+
+	board.Clear()
+	assert.Equal(t, Neutral, board.GetCell(0, 0))
+	assert.Equal(t, Empty, board.GetCell(2, 0))
+	assert.EqualValues(t, 0, board.CountMoves())
+}
+
+func TestBoardLayout(t *testing.T) {
+	board := NewBoard(WithLayout("#..#/..../X..X"), WithWinStreak(3))
+	assert.Equal(t, 4, board.W)
+	assert.Equal(t, 3, board.H)
+	assert.Equal(t, [7]int{2, 3, 3, 2}, board.Heights)
+	assert.Equal(t, Neutral, board.GetCell(3, 0))
+	assert.Equal(t, 8, board.PlayableCells())
+	assert.True(t, board.IsMirrorSymmetric())
+	assert.NotEqual(t, NewBoard(WithLayout("..../X...")).ShapeHash(), NewBoard(WithLayout("..../...X")).ShapeHash())
+
+	for _, layout := range []string{"X.../....", "..../..#.", "..A./....", "..../..."} {
+		assert.Error(t, WithLayout(layout)(NewBoard()), layout)
+	}
+}
@@ -1,6 +1,7 @@
 package common
 
-var CacheSizeLimit int = 1_500_000_000
+// DefaultCacheSizeLimit is a default cache memory limit (number of entries) of created solvers
+const DefaultCacheSizeLimit = 1_500_000_000
 
 type ICache interface {
 	Get(board *Board, depth uint) (ending Player, ok bool)
@@ -11,4 +12,15 @@ type ICache interface {
 	MaxCachedDepth() uint
 	DepthCaches() []map[uint64]Player
 	SetEntry(depth int, key uint64, value Player)
+	Stats() CacheStats
+}
+
+// CacheStats is a snapshot of cache counters
+type CacheStats struct {
+	Size uint64
+	// Hits is a number of endings taken from cache instead of solving
+	Hits        uint64
+	Clears      uint64
+	DepthSizes  []int
+	DepthClears []uint64
 }
@@ -0,0 +1,30 @@
+package common
+
+// DecodeCacheKey restores the position from cache key, having column states packed on consecutive bytes.
+// Symmetric positions share the same key, so the decoded board may be the mirrored (or rotated) one.
+// Returns the board along with the player to move.
+func DecodeCacheKey(board *Board, key uint64) (*Board, Player) {
+	decoded := board.Clone()
+	decoded.Clear()
+	for x := 0; x < board.W; x++ {
+		decoded.State[x] = key >> (8 * x) & 0xff
+	}
+	if board.Variant == PopOutVariant {
+		// player to move can't be deduced from tokens, it's kept on the most significant bit
+		return decoded, Player(key >> 63)
+	}
+	return decoded, decoded.NextPlayer()
+}
+
+// EndingName is a plain name of the game ending: winner player or a tie
+func EndingName(ending Player) string {
+	switch ending {
+	case PlayerA:
+		return string(PlayerARune)
+	case PlayerB:
+		return string(PlayerBRune)
+	case Empty:
+		return "tie"
+	}
+	return "none"
+}
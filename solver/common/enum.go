@@ -7,37 +7,37 @@ const (
 	PlayerB Player = 1 // Player B has a second move
 	Empty   Player = 2
 	NoMove  Player = 3
+	Neutral Player = 4 // Neutral token blocks the cell, not belonging to any player
 
 	PlayerARune = 'A'
 	PlayerBRune = 'B'
+	NeutralRune = 'X'
+	BlockedRune = '#' // cell outside of the board shape
 	EmptyCell   = "."
+	BlockedCell = "#"
 )
 
+// GameEnding is an outcome of the game from the player's perspective, Display renders it for the terminal
 type GameEnding string
 
 const (
-	Win      GameEnding = "\u001b[32;1mWin\u001b[0m"
-	Tie      GameEnding = "\u001b[33;1mTie\u001b[0m"
-	Lose     GameEnding = "\u001b[31;1mLose\u001b[0m"
+	Win      GameEnding = "Win"
+	Tie      GameEnding = "Tie"
+	Lose     GameEnding = "Lose"
 	NoEnding GameEnding = "None"
 )
 
-var PlayerDisplays = map[Player]string{
-	PlayerA: "\u001b[33;1mA\u001b[0m",
-	PlayerB: "\u001b[31;1mB\u001b[0m",
-	Empty:   ".",
+// PlayerNames are plain names of players, Display renders them for the terminal
+var PlayerNames = map[Player]string{
+	PlayerA: string(PlayerARune),
+	PlayerB: string(PlayerBRune),
+	Empty:   EmptyCell,
 	NoMove:  "-",
-}
-
-var ShortGameEndingDisplays = map[GameEnding]string{
-	Win:      "\u001b[32;1mW\u001b[0m",
-	Tie:      "\u001b[33;1mT\u001b[0m",
-	Lose:     "\u001b[31;1mL\u001b[0m",
-	NoEnding: "-",
+	Neutral: string(NeutralRune),
 }
 
 func (p Player) String() string {
-	return PlayerDisplays[p]
+	return PlayerNames[p]
 }
 
 func (e GameEnding) String() string {
@@ -50,4 +50,7 @@ const (
 	TrainMode  Mode = "train"
 	PlayMode   Mode = "play"
 	BrowseMode Mode = "browse"
+
+	TournamentMode Mode = "tournament"
+	CacheMode      Mode = "cache"
 )
@@ -0,0 +1,107 @@
+package common
+
+// MaxRepetitions is a number of occurrences of the same position resulting in a tie
+const MaxRepetitions = 3
+
+// GameMove is a move played in the game together with the solver's verdict about it
+type GameMove struct {
+	Move   int
+	Y      int
+	Player Player
+	// Ending is the ending of the game the move leads to according to the solver, NoMove if unknown
+	Ending Player
+
+	// position after the move
+	position gamePosition
+}
+
+// gamePosition identifies the position, as the player to move doesn't follow from the tokens in PopOut variant
+type gamePosition struct {
+	state      BoardKey
+	nextPlayer Player
+}
+
+// Game is a board with history of played moves, which can be undone and redone
+type Game struct {
+	Board *Board
+
+	start  gamePosition
+	moves  []GameMove
+	undone []GameMove
+}
+
+func NewGame(board *Board) *Game {
+	g := &Game{Board: board}
+	g.start = g.position()
+	return g
+}
+
+func (g *Game) position() gamePosition {
+	return gamePosition{state: g.Board.State, nextPlayer: g.Board.NextPlayer()}
+}
+
+// Play makes a move of the next player with the ending it leads to, moves undone before can't be redone anymore
+func (g *Game) Play(move int, ending Player) GameMove {
+	player := g.Board.NextPlayer()
+	y := g.Board.MakeMove(move, player)
+	gameMove := GameMove{Move: move, Y: y, Player: player, Ending: ending, position: g.position()}
+	g.moves = append(g.moves, gameMove)
+	g.undone = nil
+	return gameMove
+}
+
+// Undo takes back the last move, it's false if there is nothing to undo
+func (g *Game) Undo() (GameMove, bool) {
+	if len(g.moves) == 0 {
+		return GameMove{}, false
+	}
+	last := g.moves[len(g.moves)-1]
+	g.moves = g.moves[:len(g.moves)-1]
+	g.Board.UndoMove(last.Move, last.Y, last.Player)
+	g.undone = append(g.undone, last)
+	return last, true
+}
+
+// Redo plays the last undone move again, it's false if there is nothing to redo
+func (g *Game) Redo() (GameMove, bool) {
+	if len(g.undone) == 0 {
+		return GameMove{}, false
+	}
+	next := g.undone[len(g.undone)-1]
+	g.undone = g.undone[:len(g.undone)-1]
+	g.Board.MakeMove(next.Move, next.Player)
+	g.moves = append(g.moves, next)
+	return next, true
+}
+
+// Moves returns played moves from the first one
+func (g *Game) Moves() []GameMove {
+	return g.moves
+}
+
+// LastMove returns the latest played move, it's false if no move has been played
+func (g *Game) LastMove() (GameMove, bool) {
+	if len(g.moves) == 0 {
+		return GameMove{}, false
+	}
+	return g.moves[len(g.moves)-1], true
+}
+
+func (g *Game) CanRedo() bool {
+	return len(g.undone) > 0
+}
+
+// Repetitions counts occurrences of the current position in the game, including the current one
+func (g *Game) Repetitions() int {
+	current := g.position()
+	count := 0
+	if g.start == current {
+		count++
+	}
+	for _, move := range g.moves {
+		if move.position == current {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameUndoRedo(t *testing.T) {
+	game := NewGame(NewBoard(WithSize(4, 4)))
+	game.Play(1, Empty)
+	game.Play(2, PlayerA)
+	game.Play(1, NoMove)
+	assert.Equal(t, "1 2 1", movesString(game))
+
+	last, ok := game.Undo()
+	assert.True(t, ok)
+	assert.Equal(t, GameMove{Move: 1, Y: 1, Player: PlayerA, Ending: NoMove, position: last.position}, last)
+	assert.Equal(t, Empty, game.Board.GetCell(1, 1))
+	game.Undo()
+	assert.Equal(t, "1", movesString(game))
+	assert.Equal(t, PlayerB, game.Board.NextPlayer())
+
+	redone, ok := game.Redo()
+	assert.True(t, ok)
+	assert.Equal(t, PlayerB, redone.Player)
+	assert.Equal(t, PlayerA, redone.Ending)
+	assert.Equal(t, PlayerB, game.Board.GetCell(2, 0))
+	assert.True(t, game.CanRedo())
+
+	// new move drops the undone ones
+	game.Play(3, Empty)
+	assert.False(t, game.CanRedo())
+	_, ok = game.Redo()
+	assert.False(t, ok)
+	assert.Equal(t, "1 2 3", movesString(game))
+
+	for game.Board.CountMoves() > 0 {
+		game.Undo()
+	}
+	_, ok = game.Undo()
+	assert.False(t, ok)
+	_, ok = game.LastMove()
+	assert.False(t, ok)
+}
+
+func TestGameRepetitions(t *testing.T) {
+	game := NewGame(NewBoard(WithSize(2, 2), WithWinStreak(2), WithVariant(PopOutVariant)))
+	assert.Equal(t, 1, game.Repetitions())
+	game.Play(0, NoMove)
+	game.Play(1, NoMove)
+	game.Play(game.Board.PopMove(0), NoMove)
+	game.Play(game.Board.PopMove(1), NoMove)
+	assert.Equal(t, 2, game.Repetitions())
+	game.Undo()
+	assert.Equal(t, 1, game.Repetitions())
+	game.Redo()
+	assert.Equal(t, 2, game.Repetitions())
+}
+
+func movesString(game *Game) string {
+	moves := ""
+	for i, move := range game.Moves() {
+		if i > 0 {
+			moves += " "
+		}
+		moves += game.Board.MoveString(move.Move)
+	}
+	return moves
+}
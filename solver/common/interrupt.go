@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"os"
 	"os/signal"
 
@@ -9,20 +10,40 @@ import (
 	log "github.com/igrek51/log15"
 )
 
-type Interruptible interface {
-	Interrupt()
-}
-
-func HandleInterrupt(interruptible Interruptible) chan os.Signal {
+// InterruptContext is cancelled on the first SIGINT, so that CLI can stop solving with Ctrl+C.
+// Next signals are handled by default again. Stop function releases the signal handler.
+func InterruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 	go func() {
-		<-c
-		log.Debug("Signal Interrupt - stopping")
-		signal.Stop(c)
-		interruptible.Interrupt()
+		select {
+		case <-c:
+			log.Debug("Signal Interrupt - stopping")
+			signal.Stop(c)
+			cancel()
+		case <-ctx.Done():
+		}
 	}()
-	return c
+	return ctx, func() {
+		signal.Stop(c)
+		cancel()
+	}
 }
 
+// InterruptError aborts the search from deep recursion when the context of solving is done
 var InterruptError error = errors.New("Interrupt")
+
+// InterruptCause returns the error of the done context when the recovered panic is InterruptError,
+// other panics are passed on
+func InterruptCause(ctx context.Context, r interface{}, logger log.Logger) error {
+	err, ok := r.(error)
+	if !ok || !errors.Is(err, InterruptError) {
+		panic(r)
+	}
+	logger.Debug("Interrupted")
+	if ctx.Err() == nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -1,5 +1,47 @@
 package common
 
+import (
+	"fmt"
+)
+
+type MoveOrderPolicy string
+
+const (
+	// StaticMoveOrder checks middle columns first, the same order in every node
+	StaticMoveOrder MoveOrderPolicy = "static"
+	// ThreatMoveOrder plays winning moves first, skips moves handing opponent a win
+	// and sorts the rest by created threats, killer moves and history table
+	ThreatMoveOrder MoveOrderPolicy = "threat"
+)
+
+func ParseMoveOrderPolicy(name string) (MoveOrderPolicy, error) {
+	switch MoveOrderPolicy(name) {
+	case StaticMoveOrder, ThreatMoveOrder:
+		return MoveOrderPolicy(name), nil
+	}
+	return "", fmt.Errorf("unknown move order policy: %s", name)
+}
+
+// WinChecker tells if player has just won by putting a token at given cell
+type WinChecker interface {
+	HasPlayerWon(board *Board, move int, y int, player Player) bool
+}
+
+// MoveOrdering decides which moves are searched in a node and in what order
+type MoveOrdering interface {
+	// OrderedMoves returns moves of a player worth checking at given depth, best candidates first
+	OrderedMoves(board *Board, player Player, depth uint) []int
+	// Cutoff records a move that turned out to be winning at given depth
+	Cutoff(board *Board, player Player, move int, depth uint)
+}
+
+func NewMoveOrdering(policy MoveOrderPolicy, board *Board, checker WinChecker) MoveOrdering {
+	if policy == ThreatMoveOrder {
+		return NewThreatMoveOrdering(board, checker)
+	}
+	return NewStaticMoveOrdering(board)
+}
+
 func CalculateMovesOrder(board *Board) []int {
 	movesOrder := []int{}
 	for x := 0; x < board.W; x++ {
@@ -13,3 +55,126 @@ func CalculateMovesOrder(board *Board) []int {
 	}
 	return movesOrder
 }
+
+type StaticMoveOrdering struct {
+	movesOrder []int
+}
+
+func NewStaticMoveOrdering(board *Board) *StaticMoveOrdering {
+	return &StaticMoveOrdering{
+		movesOrder: CalculateMovesOrder(board),
+	}
+}
+
+func (o *StaticMoveOrdering) OrderedMoves(*Board, Player, uint) []int {
+	return o.movesOrder
+}
+
+func (o *StaticMoveOrdering) Cutoff(*Board, Player, int, uint) {}
+
+type ThreatMoveOrdering struct {
+	checker    WinChecker
+	movesOrder []int
+	h          int
+
+	// buffers for each depth, so no allocation happens during search
+	moves   [][]int
+	threats [][]int
+	bonuses [][]int
+
+	killers []int
+	history [2][]int
+}
+
+func NewThreatMoveOrdering(board *Board, checker WinChecker) *ThreatMoveOrdering {
+	depths := board.W*board.H + 1
+	o := &ThreatMoveOrdering{
+		checker:    checker,
+		movesOrder: CalculateMovesOrder(board),
+		h:          board.H,
+		moves:      make([][]int, depths),
+		threats:    make([][]int, depths),
+		bonuses:    make([][]int, depths),
+		killers:    make([]int, depths),
+	}
+	for d := 0; d < depths; d++ {
+		o.moves[d] = make([]int, 0, board.W)
+		o.threats[d] = make([]int, 0, board.W)
+		o.bonuses[d] = make([]int, 0, board.W)
+		o.killers[d] = -1
+	}
+	for p := range o.history {
+		o.history[p] = make([]int, board.W*board.H)
+	}
+	return o
+}
+
+func (o *ThreatMoveOrdering) OrderedMoves(board *Board, player Player, depth uint) []int {
+	moves := o.moves[depth][:0]
+	threats := o.threats[depth][:0]
+	bonuses := o.bonuses[depth][:0]
+	opponent := OppositePlayer(player)
+
+	for _, move := range o.movesOrder {
+		if !board.CanMakeMove(move) {
+			continue
+		}
+		y := board.Throw(move, player)
+		if o.checker.HasPlayerWon(board, move, y, player) {
+			board.Revert(move, y)
+			moves = append(moves[:0], move) // nothing can be better than winning
+			o.moves[depth] = moves
+			return moves
+		}
+		if board.CanMakeMove(move) {
+			opponentY := board.Throw(move, opponent)
+			handsWin := o.checker.HasPlayerWon(board, move, opponentY, opponent)
+			board.Revert(move, opponentY)
+			if handsWin { // move is lost anyway, no need to check it
+				board.Revert(move, y)
+				continue
+			}
+		}
+		moveThreats := o.countThreats(board, player)
+		bonus := o.history[player][move*o.h+y]
+		if o.killers[depth] == move {
+			bonus = int(^uint(0) >> 1) // killer move goes first among moves with the same threats
+		}
+		board.Revert(move, y)
+
+		// insertion sort, keeping static order for equal moves
+		i := len(moves)
+		moves = append(moves, move)
+		threats = append(threats, moveThreats)
+		bonuses = append(bonuses, bonus)
+		for i > 0 && (threats[i-1] < moveThreats || (threats[i-1] == moveThreats && bonuses[i-1] < bonus)) {
+			moves[i], threats[i], bonuses[i] = moves[i-1], threats[i-1], bonuses[i-1]
+			i--
+		}
+		moves[i], threats[i], bonuses[i] = move, moveThreats, bonus
+	}
+
+	o.moves[depth], o.threats[depth], o.bonuses[depth] = moves, threats, bonuses
+	return moves
+}
+
+// countThreats counts columns where player could win with the next move
+func (o *ThreatMoveOrdering) countThreats(board *Board, player Player) int {
+	threats := 0
+	for x := 0; x < board.W; x++ {
+		if board.CanMakeMove(x) {
+			y := board.Throw(x, player)
+			if o.checker.HasPlayerWon(board, x, y, player) {
+				threats++
+			}
+			board.Revert(x, y)
+		}
+	}
+	return threats
+}
+
+func (o *ThreatMoveOrdering) Cutoff(board *Board, player Player, move int, depth uint) {
+	o.killers[depth] = move
+	remaining := len(o.killers) - int(depth)
+	o.history[player][move*o.h+board.StackSize(move)] += remaining * remaining
+}
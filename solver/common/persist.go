@@ -4,52 +4,87 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"time"
 
 	log "github.com/igrek51/log15"
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 
 	pb "github.com/igrek51/connect4solver/proto"
 )
 
-func SaveCache(cache ICache, boardW, boardH int) error {
-	maxDepth := int(cache.MaxCachedDepth() / 2)
-	filename := cacheFilename(boardW, boardH)
+// SaveCacheFileFormat saves cached endings (up to the half of the max cached depth) to given file in chosen format
+func SaveCacheFileFormat(cache ICache, filename string, format CacheFormat, logger log.Logger) error {
+	return saveCacheFileDepths(cache, filename, format, int(cache.MaxCachedDepth()/2), logger)
+}
+
+// SaveFullCacheFile saves cached endings of all depths to given file in chosen format,
+// eg. pruned cache, which is small, but has to contain whole strategy
+func SaveFullCacheFile(cache ICache, filename string, format CacheFormat, logger log.Logger) error {
+	return saveCacheFileDepths(cache, filename, format, len(cache.DepthCaches()), logger)
+}
 
-	log.Debug("Encoding to protobuf struct...", log.Ctx{
+func saveCacheFileDepths(cache ICache, filename string, format CacheFormat, maxDepth int, logger log.Logger) error {
+	logger.Debug("Encoding cache...", log.Ctx{
 		"filename": filename,
+		"format":   format,
 	})
 	startTime := time.Now()
-	protoCache, entriesLen := cacheToProto(cache, maxDepth, boardW, boardH)
-	log.Debug("Marshalling protobuf...", log.Ctx{
-		"splitTime": time.Since(startTime),
-		"entries":   entriesLen,
-	})
-	outBytes, err := proto.Marshal(protoCache)
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal cache to proto")
+	var outBytes []byte
+	var entriesLen uint64
+	if format == ProtobufCacheFormat {
+		var protoCache *pb.DepthCaches
+		protoCache, entriesLen = cacheToProto(cache, maxDepth)
+		logger.Debug("Marshalling protobuf...", log.Ctx{
+			"splitTime": time.Since(startTime),
+			"entries":   entriesLen,
+		})
+		var err error
+		outBytes, err = proto.Marshal(protoCache)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal cache to proto")
+		}
+	} else {
+		var err error
+		outBytes, entriesLen, err = encodeCompactCache(cache, maxDepth, format == CompactGzipCacheFormat)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode compact cache")
+		}
 	}
-	log.Debug("Saving cache file...", log.Ctx{
+	logger.Debug("Saving cache file...", log.Ctx{
 		"splitTime": time.Since(startTime),
 		"bytes":     len(outBytes),
 	})
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return errors.Wrap(err, "failed to create cache directory")
+	}
 	if err := ioutil.WriteFile(filename, outBytes, 0644); err != nil {
 		return errors.Wrap(err, "failed to write to file")
 	}
-	log.Debug("Cache saved", log.Ctx{
+	// raw size of keys is 8 bytes per entry
+	rawBytes := 8 * entriesLen
+	reduction := 0.0
+	if rawBytes > 0 {
+		reduction = 100 * (1 - float64(len(outBytes))/float64(rawBytes))
+	}
+	logger.Debug("Cache saved", log.Ctx{
 		"filename":     filename,
 		"savedEntries": entriesLen,
 		"allEntries":   cache.Size(),
 		"maxDepth":     maxDepth,
+		"bytes":        len(outBytes),
+		"rawBytes":     rawBytes,
+		"reduction":    fmt.Sprintf("%.1f%%", reduction),
 		"duration":     time.Since(startTime),
 	})
 	return nil
 }
 
-func LoadCache(cache ICache, boardW, boardH int) error {
-	filename := cacheFilename(boardW, boardH)
-	log.Debug("Loading cache file...", log.Ctx{
+// LoadCacheFile loads cached endings from given file (in any format) into the cache
+func LoadCacheFile(cache ICache, filename string, logger log.Logger) error {
+	logger.Debug("Loading cache file...", log.Ctx{
 		"filename": filename,
 	})
 	startTime := time.Now()
@@ -57,21 +92,36 @@ func LoadCache(cache ICache, boardW, boardH int) error {
 	if err != nil {
 		return errors.Wrap(err, "error reading file")
 	}
-	log.Debug("Unmarshalling protobuf...", log.Ctx{
-		"splitTime": time.Since(startTime),
-		"bytes":     len(in),
-	})
-	dephtCaches := &pb.DepthCaches{}
-	if err := proto.Unmarshal(in, dephtCaches); err != nil {
-		return errors.Wrap(err, "failed to unmarshal protobuf")
-	}
-	log.Debug("Decoding from protobuf struct...", log.Ctx{
-		"splitTime": time.Since(startTime),
-	})
+	if isCompactCache(in) {
+		logger.Debug("Decoding compact cache...", log.Ctx{
+			"splitTime": time.Since(startTime),
+			"bytes":     len(in),
+		})
+		err := visitDepthBlocks(in, func(depth int, block depthBlock) error {
+			return errors.Wrapf(block.decode(func(key uint64, ending Player) {
+				cache.SetEntry(depth, key, ending)
+			}), "failed to decode depth %d", depth)
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		logger.Debug("Unmarshalling protobuf...", log.Ctx{
+			"splitTime": time.Since(startTime),
+			"bytes":     len(in),
+		})
+		dephtCaches := &pb.DepthCaches{}
+		if err := proto.Unmarshal(in, dephtCaches); err != nil {
+			return errors.Wrap(err, "failed to unmarshal protobuf")
+		}
+		logger.Debug("Decoding from protobuf struct...", log.Ctx{
+			"splitTime": time.Since(startTime),
+		})
 
-	protoToCache(dephtCaches, cache, boardW, boardH)
+		protoToCache(dephtCaches, cache)
+	}
 
-	log.Debug("Cache loaded", log.Ctx{
+	logger.Debug("Cache loaded", log.Ctx{
 		"filename": filename,
 		"entries":  cache.Size(),
 		"duration": time.Since(startTime),
@@ -79,29 +129,9 @@ func LoadCache(cache ICache, boardW, boardH int) error {
 	return nil
 }
 
-func MustSaveCache(cache ICache, boardW, boardH int) {
-	err := SaveCache(cache, boardW, boardH)
-	if err != nil {
-		panic(errors.Wrap(err, "saving cache"))
-	}
-}
-
-func MustLoadCache(cache ICache, boardW, boardH int) {
-	err := LoadCache(cache, boardW, boardH)
-	if err != nil {
-		panic(errors.Wrap(err, "loading cache"))
-	}
-}
-
-func CacheFileExists(board *Board) bool {
-	filename := cacheFilename(board.W, board.H)
-	_, err := os.Stat(filename)
-	return err == nil
-}
-
-func cacheToProto(cache ICache, maxDepth int, boardW int, boardH int) (*pb.DepthCaches, uint64) {
+func cacheToProto(cache ICache, maxDepth int) (*pb.DepthCaches, uint64) {
 	dephtCaches := &pb.DepthCaches{
-		DepthCaches: make([]*pb.DepthCache, boardW*boardH),
+		DepthCaches: make([]*pb.DepthCache, len(cache.DepthCaches())),
 	}
 	entriesLen := uint64(0)
 	for d, depthCache := range cache.DepthCaches() {
@@ -129,7 +159,7 @@ func cacheToProto(cache ICache, maxDepth int, boardW int, boardH int) (*pb.Depth
 	return dephtCaches, entriesLen
 }
 
-func protoToCache(dephtCaches *pb.DepthCaches, cache ICache, boardW int, boardH int) {
+func protoToCache(dephtCaches *pb.DepthCaches, cache ICache) {
 	for d, depthCache := range dephtCaches.DepthCaches {
 		for _, k := range depthCache.BoardsPlayerA {
 			cache.SetEntry(d, k, PlayerA)
@@ -143,6 +173,156 @@ func protoToCache(dephtCaches *pb.DepthCaches, cache ICache, boardW int, boardH
 	}
 }
 
-func cacheFilename(boardW, boardH int) string {
-	return fmt.Sprintf("cache/cache_%dx%d.protobuf", boardW, boardH)
+// ReadCacheFileDepths reads cached endings of selected depths (all of them if none given) from the file.
+// Depth caches are decoded one by one, skipping the ones not selected, so the whole file doesn't have to be unmarshalled.
+// Returned slice is indexed by depth, not selected depths are nil.
+func ReadCacheFileDepths(filename string, depths ...int) ([]map[uint64]Player, error) {
+	in, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading file")
+	}
+	selected := map[int]bool{}
+	for _, depth := range depths {
+		selected[depth] = true
+	}
+	depthCaches := []map[uint64]Player{}
+	err = visitDepthBlocks(in, func(depth int, block depthBlock) error {
+		depthCaches = append(depthCaches, nil)
+		if len(depths) > 0 && !selected[depth] {
+			return nil
+		}
+		depthCache := make(map[uint64]Player)
+		err := block.decode(func(key uint64, ending Player) {
+			depthCache[key] = ending
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode depth %d", depth)
+		}
+		depthCaches[depth] = depthCache
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return depthCaches, nil
+}
+
+// CacheFileDepthSizes returns number of bytes taken by each depth in the cache file (before compression)
+func CacheFileDepthSizes(filename string) ([]int, error) {
+	in, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading file")
+	}
+	sizes := []int{}
+	err = visitDepthBlocks(in, func(depth int, block depthBlock) error {
+		sizes = append(sizes, len(block.raw))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sizes, nil
+}
+
+// ValidateCacheData checks if the content of cache file in any format can be decoded
+func ValidateCacheData(in []byte) error {
+	if len(in) == 0 {
+		return errors.New("cache file is empty")
+	}
+	return visitDepthBlocks(in, func(depth int, block depthBlock) error {
+		return errors.Wrapf(block.decode(func(uint64, Player) {}), "failed to decode depth %d", depth)
+	})
+}
+
+// depthBlock is an encoded cache of single depth, either protobuf message or compact block
+type depthBlock struct {
+	raw     []byte
+	compact bool
+}
+
+func (b depthBlock) decode(visit func(key uint64, ending Player)) error {
+	if b.compact {
+		return decodeCompactBlock(b.raw, visit)
+	}
+	protoCache := &pb.DepthCache{}
+	if err := proto.Unmarshal(b.raw, protoCache); err != nil {
+		return errors.Wrap(err, "failed to unmarshal protobuf")
+	}
+	for _, k := range protoCache.BoardsPlayerA {
+		visit(k, PlayerA)
+	}
+	for _, k := range protoCache.BoardsPlayerB {
+		visit(k, PlayerB)
+	}
+	for _, k := range protoCache.BoardsTie {
+		visit(k, Empty)
+	}
+	return nil
+}
+
+// visitDepthBlocks visits encoded caches of consecutive depths in a file of any format
+func visitDepthBlocks(in []byte, visit func(depth int, block depthBlock) error) error {
+	if isCompactCache(in) {
+		blocks, err := compactDepthBlocks(in)
+		if err != nil {
+			return errors.Wrap(err, "failed to decode compact cache")
+		}
+		for d, block := range blocks {
+			if err := visit(d, depthBlock{raw: block, compact: true}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return scanDepthCaches(in, func(depth int, message []byte) error {
+		return visit(depth, depthBlock{raw: message})
+	})
+}
+
+// scanDepthCaches walks through the encoded DepthCaches message, visiting raw messages of consecutive depths
+func scanDepthCaches(in []byte, visit func(depth int, message []byte) error) error {
+	depth := 0
+	for len(in) > 0 {
+		num, typ, n := protowire.ConsumeTag(in)
+		if n < 0 {
+			return errors.Wrap(protowire.ParseError(n), "invalid protobuf tag")
+		}
+		in = in[n:]
+		if num != depthCachesField || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, in)
+			if n < 0 {
+				return errors.Wrap(protowire.ParseError(n), "invalid protobuf field")
+			}
+			in = in[n:]
+			continue
+		}
+		message, n := protowire.ConsumeBytes(in)
+		if n < 0 {
+			return errors.Wrap(protowire.ParseError(n), "invalid depth cache")
+		}
+		in = in[n:]
+		if err := visit(depth, message); err != nil {
+			return err
+		}
+		depth++
+	}
+	return nil
+}
+
+// depthCachesField is the number of repeated depthCaches field in DepthCaches message
+const depthCachesField protowire.Number = 1
+
+// CacheIdentitySuffix distinguishes cache files of boards with non-default rules
+func CacheIdentitySuffix(board *Board) string {
+	suffix := ""
+	if board.Variant != StandardVariant {
+		suffix += "_" + string(board.Variant)
+	}
+	if !board.Turns.IsAlternating() {
+		suffix += "_turns" + board.Turns.String()
+	}
+	if !board.IsRectangular() {
+		suffix += "_shape" + board.ShapeHash()
+	}
+	return suffix
 }
@@ -0,0 +1,173 @@
+package common
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+type CacheFormat string
+
+const (
+	ProtobufCacheFormat CacheFormat = "protobuf"
+	// CompactCacheFormat keeps sorted keys of each depth and ending, delta encoded as varints
+	CompactCacheFormat CacheFormat = "compact"
+	// CompactGzipCacheFormat is a compact format compressed with gzip
+	CompactGzipCacheFormat CacheFormat = "compact-gzip"
+)
+
+func ParseCacheFormat(format string) (CacheFormat, error) {
+	switch CacheFormat(format) {
+	case ProtobufCacheFormat, CompactCacheFormat, CompactGzipCacheFormat:
+		return CacheFormat(format), nil
+	}
+	return "", fmt.Errorf("unknown cache format: %s", format)
+}
+
+// compact file starts with magic bytes, followed by version and compression bytes
+var compactCacheMagic = []byte("C4CC")
+
+const (
+	compactCacheVersion = 1
+	compactHeaderSize   = 6
+
+	noCompression   = 0
+	gzipCompression = 1
+)
+
+// endings stored in each depth block, in order
+var compactEndings = []Player{PlayerA, PlayerB, Empty}
+
+func isCompactCache(in []byte) bool {
+	return bytes.HasPrefix(in, compactCacheMagic)
+}
+
+// encodeCompactCache encodes depths up to maxDepth: number of depths, then for each depth length of its block and the block.
+// Block contains keys grouped by ending (A, B, tie): number of keys and sorted keys as deltas from the previous one.
+func encodeCompactCache(cache ICache, maxDepth int, compress bool) ([]byte, uint64, error) {
+	depthCaches := cache.DepthCaches()
+	depths := len(depthCaches)
+	if maxDepth+1 < depths {
+		depths = maxDepth + 1
+	}
+	body := appendUvarint(nil, uint64(depths))
+	entriesLen := uint64(0)
+	for d := 0; d < depths; d++ {
+		block := encodeCompactBlock(depthCaches[d])
+		body = appendUvarint(body, uint64(len(block)))
+		body = append(body, block...)
+		entriesLen += uint64(len(depthCaches[d]))
+	}
+
+	out := append([]byte{}, compactCacheMagic...)
+	out = append(out, compactCacheVersion)
+	if !compress {
+		return append(append(out, noCompression), body...), entriesLen, nil
+	}
+	buffer := bytes.NewBuffer(append(out, gzipCompression))
+	writer, err := gzip.NewWriterLevel(buffer, gzip.BestCompression)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "creating gzip writer")
+	}
+	if _, err := writer.Write(body); err != nil {
+		return nil, 0, errors.Wrap(err, "compressing cache")
+	}
+	if err := writer.Close(); err != nil {
+		return nil, 0, errors.Wrap(err, "compressing cache")
+	}
+	return buffer.Bytes(), entriesLen, nil
+}
+
+func encodeCompactBlock(depthCache map[uint64]Player) []byte {
+	block := []byte{}
+	for _, ending := range compactEndings {
+		keys := []uint64{}
+		for k, v := range depthCache {
+			if v == ending {
+				keys = append(keys, k)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		block = appendUvarint(block, uint64(len(keys)))
+		previous := uint64(0)
+		for _, k := range keys {
+			block = appendUvarint(block, k-previous)
+			previous = k
+		}
+	}
+	return block
+}
+
+// compactDepthBlocks decompresses the file and splits it into encoded blocks of consecutive depths
+func compactDepthBlocks(in []byte) ([][]byte, error) {
+	if len(in) < compactHeaderSize {
+		return nil, errors.New("compact cache header is too short")
+	}
+	if version := in[len(compactCacheMagic)]; version != compactCacheVersion {
+		return nil, fmt.Errorf("unsupported compact cache version: %d", version)
+	}
+	body := in[compactHeaderSize:]
+	switch compression := in[compactHeaderSize-1]; compression {
+	case noCompression:
+	case gzipCompression:
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, errors.Wrap(err, "reading gzip stream")
+		}
+		body, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "decompressing cache")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression: %d", compression)
+	}
+
+	depths, n := binary.Uvarint(body)
+	if n <= 0 {
+		return nil, errors.New("invalid number of depths")
+	}
+	body = body[n:]
+	blocks := [][]byte{}
+	for d := uint64(0); d < depths; d++ {
+		blockLen, n := binary.Uvarint(body)
+		if n <= 0 || uint64(len(body)-n) < blockLen {
+			return nil, fmt.Errorf("invalid block of depth %d", d)
+		}
+		blocks = append(blocks, body[n:n+int(blockLen)])
+		body = body[n+int(blockLen):]
+	}
+	return blocks, nil
+}
+
+// decodeCompactBlock visits entries of the depth block
+func decodeCompactBlock(block []byte, visit func(key uint64, ending Player)) error {
+	for _, ending := range compactEndings {
+		count, n := binary.Uvarint(block)
+		if n <= 0 {
+			return errors.New("invalid number of keys")
+		}
+		block = block[n:]
+		key := uint64(0)
+		for i := uint64(0); i < count; i++ {
+			delta, n := binary.Uvarint(block)
+			if n <= 0 {
+				return errors.New("invalid key delta")
+			}
+			block = block[n:]
+			key += delta
+			visit(key, ending)
+		}
+	}
+	return nil
+}
+
+func appendUvarint(buffer []byte, value uint64) []byte {
+	var encoded [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(encoded[:], value)
+	return append(buffer, encoded[:n]...)
+}
@@ -1,30 +1,40 @@
 package common
 
 import (
-	"fmt"
-	"os"
 	"time"
-
-	"github.com/schollz/progressbar/v3"
 )
 
-const ProgressBarResolution = 1_000_000_000
+// Progress is a snapshot of the solving state, reported periodically
+type Progress struct {
+	// Fraction of the solved search space, from 0 to 1
+	Fraction float64
+	// ETA is an estimated time left, zero if unknown
+	ETA              time.Duration
+	Iterations       uint64
+	IterationsPerSec uint64
+	Cache            CacheStats
+	// Board is a copy of the currently considered board
+	Board *Board
+}
+
+// ProgressListener receives progress of solving.
+// It's called from the solving goroutine, so it should return quickly.
+type ProgressListener interface {
+	OnProgress(progress Progress)
+}
+
+// ProgressFunc adapts a function to ProgressListener
+type ProgressFunc func(progress Progress)
+
+func (f ProgressFunc) OnProgress(progress Progress) {
+	f(progress)
+}
+
+// ProgressListeners pass progress to all the listeners
+type ProgressListeners []ProgressListener
 
-func NewProgressBar() *progressbar.ProgressBar {
-	bar := progressbar.NewOptions64(
-		ProgressBarResolution,
-		progressbar.OptionSetDescription(""),
-		progressbar.OptionSetWriter(os.Stderr),
-		progressbar.OptionSetWidth(10),
-		progressbar.OptionThrottle(65*time.Millisecond),
-		progressbar.OptionOnCompletion(func() {
-			fmt.Fprint(os.Stderr, "\n")
-		}),
-		progressbar.OptionSpinnerType(14),
-		progressbar.OptionFullWidth(),
-		progressbar.OptionSetPredictTime(true),
-		progressbar.OptionShowCount(),
-	)
-	bar.RenderBlank()
-	return bar
+func (l ProgressListeners) OnProgress(progress Progress) {
+	for _, listener := range l {
+		listener.OnProgress(progress)
+	}
 }
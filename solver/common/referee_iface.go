@@ -0,0 +1,29 @@
+package common
+
+import (
+	"fmt"
+)
+
+type IReferee interface {
+	// HasPlayerWon checks if player has just won by putting a token at given cell
+	HasPlayerWon(board *Board, move int, y int, player Player) bool
+	// HasWinner checks whole board, returning the winner or Empty
+	HasWinner(board *Board) Player
+}
+
+type RefereeKind string
+
+const (
+	// LookupRefereeKind uses pre-calculated tables of winning rows and diagonals
+	LookupRefereeKind RefereeKind = "lookup"
+	// BitboardRefereeKind finds streaks with bitwise shifts of the whole board
+	BitboardRefereeKind RefereeKind = "bitboard"
+)
+
+func ParseRefereeKind(name string) (RefereeKind, error) {
+	switch RefereeKind(name) {
+	case LookupRefereeKind, BitboardRefereeKind:
+		return RefereeKind(name), nil
+	}
+	return "", fmt.Errorf("unknown referee: %s", name)
+}
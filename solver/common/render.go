@@ -0,0 +1,188 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Theme is a set of symbols for board cells
+type Theme string
+
+const (
+	// AsciiTheme shows players as letters
+	AsciiTheme Theme = "ascii"
+	// UnicodeTheme shows players as filled and hollow discs
+	UnicodeTheme Theme = "unicode"
+)
+
+func ParseTheme(name string) (Theme, error) {
+	switch Theme(name) {
+	case AsciiTheme, UnicodeTheme:
+		return Theme(name), nil
+	}
+	return "", fmt.Errorf("unknown theme: %s", name)
+}
+
+// ColorMode tells when ANSI colors are used
+type ColorMode string
+
+const (
+	// ColorAuto colors output only on a terminal, unless NO_COLOR is set
+	ColorAuto   ColorMode = "auto"
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+)
+
+func ParseColorMode(mode string) (ColorMode, error) {
+	switch ColorMode(mode) {
+	case ColorAuto, ColorAlways, ColorNever:
+		return ColorMode(mode), nil
+	}
+	return "", fmt.Errorf("unknown color mode: %s", mode)
+}
+
+// NoColorEnv disables colors in auto mode when set to a non-empty value, see https://no-color.org
+const NoColorEnv = "NO_COLOR"
+
+var themeSymbols = map[Theme]map[Player]string{
+	AsciiTheme: {
+		PlayerA: string(PlayerARune),
+		PlayerB: string(PlayerBRune),
+		Empty:   EmptyCell,
+		NoMove:  "-",
+		Neutral: string(NeutralRune),
+	},
+	UnicodeTheme: {
+		PlayerA: "●",
+		PlayerB: "○",
+		Empty:   "·",
+		NoMove:  "-",
+		Neutral: "■",
+	},
+}
+
+var shortEndings = map[GameEnding]string{
+	Win:      "W",
+	Tie:      "T",
+	Lose:     "L",
+	NoEnding: "-",
+}
+
+var playerColors = map[Player]string{
+	PlayerA: "33;1",
+	PlayerB: "31;1",
+}
+
+var endingColors = map[GameEnding]string{
+	Win:  "32;1",
+	Tie:  "33;1",
+	Lose: "31;1",
+}
+
+// Renderer shows players, endings and boards for the terminal
+type Renderer struct {
+	Theme Theme
+	Color bool
+}
+
+// Display is the renderer used for the terminal output, it's plain until the CLI configures theme and colors
+var Display = &Renderer{Theme: AsciiTheme}
+
+// NewRenderer creates renderer with given theme, deciding on colors for stdout
+func NewRenderer(theme Theme, colorMode ColorMode) *Renderer {
+	return &Renderer{
+		Theme: theme,
+		Color: UseColor(colorMode, os.Stdout),
+	}
+}
+
+// UseColor tells if output to the file should be colored in given mode
+func UseColor(mode ColorMode, file *os.File) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	return os.Getenv(NoColorEnv) == "" && IsTerminal(file)
+}
+
+// IsTerminal tells if the file is a terminal (character device)
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (r *Renderer) Player(player Player) string {
+	return r.colored(themeSymbols[r.Theme][player], playerColors[player])
+}
+
+func (r *Renderer) Ending(ending GameEnding) string {
+	return r.colored(string(ending), endingColors[ending])
+}
+
+func (r *Renderer) ShortEnding(ending GameEnding) string {
+	return r.colored(shortEndings[ending], endingColors[ending])
+}
+
+func (r *Renderer) colored(text string, color string) string {
+	if !r.Color || color == "" {
+		return text
+	}
+	return "\u001b[" + color + "m" + text + "\u001b[0m"
+}
+
+// Highlight emphasizes the text with reversed colors
+func (r *Renderer) Highlight(text string) string {
+	return r.colored(text, "7")
+}
+
+// Board renders the board in a frame, with column numbers below
+func (r *Renderer) Board(b *Board) string {
+	return r.BoardCells(b, func(x, y int, cell Player) string {
+		return r.Player(cell)
+	})
+}
+
+// BoardCells renders the board in a frame, showing each cell with given function
+func (r *Renderer) BoardCells(b *Board, render func(x, y int, cell Player) string) string {
+	lines := []string{}
+	var line string
+
+	line = "+-"
+	for i := 0; i < b.W; i++ {
+		line += "--"
+	}
+	line += "+"
+	lines = append(lines, line)
+
+	for y := b.H - 1; y >= 0; y-- {
+		rowCells := []string{}
+		for x := 0; x < b.W; x++ {
+			if y >= b.Heights[x] {
+				rowCells = append(rowCells, BlockedCell)
+				continue
+			}
+			rowCells = append(rowCells, render(x, y, b.GetCell(x, y)))
+		}
+		line = "| " + strings.Join(rowCells, " ") + " |"
+		lines = append(lines, line)
+	}
+
+	line = "+-"
+	for i := 0; i < b.W; i++ {
+		line += "--"
+	}
+	line += "+"
+	lines = append(lines, line)
+
+	coordinates := []string{}
+	for x := 0; x < b.W; x++ {
+		coordinates = append(coordinates, fmt.Sprint(x))
+	}
+	line = "| " + strings.Join(coordinates, " ") + " |"
+	lines = append(lines, line)
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderPlain(t *testing.T) {
+	renderer := &Renderer{Theme: AsciiTheme}
+	assert.Equal(t, "A", renderer.Player(PlayerA))
+	assert.Equal(t, "Win", renderer.Ending(Win))
+	assert.Equal(t, "L", renderer.ShortEnding(Lose))
+	assert.Equal(t, "-", renderer.ShortEnding(NoEnding))
+
+	board := ParseBoard(`
+	...
+	AB.
+	`)
+	assert.Equal(t, "+-------+\n| . . . |\n| A B . |\n+-------+\n| 0 1 2 |", renderer.Board(board))
+}
+
+func TestRenderColoredUnicode(t *testing.T) {
+	renderer := &Renderer{Theme: UnicodeTheme, Color: true}
+	assert.Equal(t, "\u001b[33;1m●\u001b[0m", renderer.Player(PlayerA))
+	assert.Equal(t, "\u001b[31;1m○\u001b[0m", renderer.Player(PlayerB))
+	assert.Equal(t, "·", renderer.Player(Empty))
+	assert.Equal(t, "\u001b[32;1mW\u001b[0m", renderer.ShortEnding(Win))
+	assert.Equal(t, "None", renderer.Ending(NoEnding))
+	assert.Equal(t, "Tie", Tie.String())
+	assert.Equal(t, "B", PlayerB.String())
+}
+
+func TestUseColor(t *testing.T) {
+	file, err := ioutil.TempFile("", "c4solver")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	assert.True(t, UseColor(ColorAlways, file))
+	assert.False(t, UseColor(ColorNever, file))
+	assert.False(t, UseColor(ColorAuto, file), "file is not a terminal")
+
+	previous, set := os.LookupEnv(NoColorEnv)
+	defer func() {
+		if set {
+			os.Setenv(NoColorEnv, previous)
+		} else {
+			os.Unsetenv(NoColorEnv)
+		}
+	}()
+	os.Setenv(NoColorEnv, "1")
+	assert.False(t, UseColor(ColorAuto, os.Stdout))
+	assert.True(t, UseColor(ColorAlways, os.Stdout))
+
+	_, err = ParseColorMode("sometimes")
+	assert.Error(t, err)
+	_, err = ParseTheme("emoji")
+	assert.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package common
+
+// Rules decide the outcome of completing a winning streak
+type Rules struct {
+	misere bool
+}
+
+func NewRules(variant Variant) *Rules {
+	return &Rules{
+		misere: variant == MisereVariant,
+	}
+}
+
+// LineWinner returns the winner of the game when player completes a line
+func (r *Rules) LineWinner(player Player) Player {
+	if r.misere {
+		return OppositePlayer(player)
+	}
+	return player
+}
+
+// LineWins tells if completing a line is desired by its owner,
+// otherwise heuristics based on winning threats don't apply
+func (r *Rules) LineWins() bool {
+	return !r.misere
+}
+
+// LineChecker tells if player has completed a line with the token at given cell
+type LineChecker interface {
+	HasPlayerWon(board *Board, move int, y int, player Player) bool
+}
+
+// MoveWinner returns the winner of the game after player's move or Empty if it goes on.
+// Popping out may complete lines of both players, then the opponent's line counts too.
+func (r *Rules) MoveWinner(checker LineChecker, board *Board, move int, y int, player Player) Player {
+	if checker.HasPlayerWon(board, move, y, player) {
+		return r.LineWinner(player)
+	}
+	opponent := OppositePlayer(player)
+	if board.IsPopMove(move) && checker.HasPlayerWon(board, move, y, opponent) {
+		return r.LineWinner(opponent)
+	}
+	return Empty
+}
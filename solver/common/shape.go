@@ -0,0 +1,166 @@
+package common
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+)
+
+// WithColumnHeights makes a board of non-rectangular shape, having columns of different heights
+func WithColumnHeights(heights ...int) Option {
+	return func(b *Board) error {
+		if len(heights) < 1 || len(heights) > len(b.Heights) {
+			return fmt.Errorf("number of columns should be in range [1-%d], got %d", len(b.Heights), len(heights))
+		}
+		b.W = len(heights)
+		b.H = 0
+		b.Heights = [7]int{}
+		for x, height := range heights {
+			if height < 0 || height > 6 {
+				return fmt.Errorf("column height should be in range [0-6], got %d", height)
+			}
+			b.Heights[x] = height
+			if height > b.H {
+				b.H = height
+			}
+		}
+		b.customHeights = true
+		return nil
+	}
+}
+
+// WithLayout sets board shape from rows of cells (top row first, separated by "/" or new lines):
+// "." - empty cell, "X" - neutral token blocking the cell, "#" - cell outside of the board shape.
+// Neutral tokens are subject to gravity, so they have to lie on the bottom or on other neutral tokens.
+func WithLayout(layout string) Option {
+	return func(b *Board) error {
+		lines := parseLayoutLines(strings.ReplaceAll(layout, "/", "\n"))
+		for _, line := range lines[1:] {
+			if len(line) != len(lines[0]) {
+				return fmt.Errorf("layout rows should have equal lengths")
+			}
+		}
+		heights, _ := layoutHeights(lines)
+		if err := WithColumnHeights(heights...)(b); err != nil {
+			return err
+		}
+		b.Neutral = BoardKey{}
+		for y, line := range lines {
+			for x, cell := range line {
+				if y >= heights[x] && cell != BlockedRune {
+					return fmt.Errorf("cells above the blocked one should be blocked too, column %d", x)
+				}
+				switch {
+				case cell == NeutralRune:
+					if y > 0 && b.Neutral[x]>>(y-1)&0b1 == 0 {
+						return fmt.Errorf("neutral token at column %d should lie on other neutral token", x)
+					}
+					b.Neutral[x] |= 1 << y
+				case cell != BlockedRune && string(cell) != EmptyCell:
+					return fmt.Errorf("unexpected cell in layout: %c", cell)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// parseLayoutLines splits rows of cells, bottom row goes first
+func parseLayoutLines(txt string) []string {
+	txt = strings.TrimSpace(txt)
+	lines := strings.Split(txt, "\n")
+	lines = ReverseLines(lines)
+	for i, line := range lines {
+		line = strings.ReplaceAll(line, " ", "")
+		lines[i] = strings.ReplaceAll(line, "\t", "")
+	}
+	return lines
+}
+
+// layoutHeights finds column heights, tells whether any cell is outside of the board shape
+func layoutHeights(lines []string) (heights []int, shaped bool) {
+	heights = make([]int, len(lines[0]))
+	for x := range heights {
+		heights[x] = len(lines)
+		for y, line := range lines {
+			if x < len(line) && line[x] == BlockedRune {
+				heights[x] = y
+				shaped = true
+				break
+			}
+		}
+	}
+	return heights, shaped
+}
+
+// ThrowNeutral drops neutral token to the column
+func (b *Board) ThrowNeutral(x int) int {
+	y := b.Throw(x, PlayerA)
+	b.Neutral[x] |= 1 << y
+	return y
+}
+
+// PlayableCells is a number of cells that can be taken by players' tokens
+func (b *Board) PlayableCells() int {
+	cells := 0
+	for x := 0; x < b.W; x++ {
+		cells += b.Heights[x] - bits.OnesCount64(b.Neutral[x])
+	}
+	return cells
+}
+
+// IsRectangular tells if all columns have the same height and there are no neutral tokens
+func (b *Board) IsRectangular() bool {
+	for x := 0; x < b.W; x++ {
+		if b.Heights[x] != b.H || b.Neutral[x] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsMirrorSymmetric tells if the board shape looks the same after mirroring, so mirrored positions are equivalent
+func (b *Board) IsMirrorSymmetric() bool {
+	for x := 0; x < b.W/2; x++ {
+		if b.Heights[x] != b.Heights[b.W-1-x] || b.Neutral[x] != b.Neutral[b.W-1-x] {
+			return false
+		}
+	}
+	return true
+}
+
+// ShapeHash identifies column heights and neutral tokens of the board
+func (b *Board) ShapeHash() string {
+	hash := fnv.New32a()
+	for x := 0; x < b.W; x++ {
+		fmt.Fprintf(hash, "%d:%x,", b.Heights[x], b.Neutral[x])
+	}
+	return fmt.Sprintf("%08x", hash.Sum32())
+}
+
+// LayoutString renders cells in a single line, rows from the top separated by "/", eg. "#..#/.AB./XBAA"
+func (b *Board) LayoutString() string {
+	rows := []string{}
+	for y := b.H - 1; y >= 0; y-- {
+		row := []rune{}
+		for x := 0; x < b.W; x++ {
+			if y >= b.Heights[x] {
+				row = append(row, BlockedRune)
+				continue
+			}
+			switch b.GetCell(x, y) {
+			case PlayerA:
+				row = append(row, PlayerARune)
+			case PlayerB:
+				row = append(row, PlayerBRune)
+			case Neutral:
+				row = append(row, NeutralRune)
+			default:
+				row = append(row, []rune(EmptyCell)...)
+			}
+		}
+		rows = append(rows, string(row))
+	}
+	return strings.Join(rows, "/")
+}
@@ -0,0 +1,119 @@
+package common
+
+import (
+	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
+)
+
+// SolverConfig keeps tunable search features shared by solver implementations
+type SolverConfig struct {
+	MoveOrder MoveOrderPolicy
+	// ForcedMoves enables detecting forced blocks and double threats before recursing
+	ForcedMoves bool
+	Referee     RefereeKind
+	// Cache is an injected cache of the same solver implementation, new one is created if nil
+	Cache ICache
+	// CacheLimit is a cache memory limit (number of entries) of created cache
+	CacheLimit int
+	// Progress receives progress of solving, nothing is reported if nil
+	Progress ProgressListener
+	// Logger receives log records of the solver and its cache, they're discarded by default
+	Logger log.Logger
+}
+
+type SolverOption func(*SolverConfig) error
+
+func NewSolverConfig(options ...SolverOption) *SolverConfig {
+	// set defaults
+	config := &SolverConfig{
+		MoveOrder:   StaticMoveOrder,
+		ForcedMoves: true,
+		Referee:     LookupRefereeKind,
+		CacheLimit:  DefaultCacheSizeLimit,
+		Logger:      DiscardLogger(),
+	}
+
+	// apply options
+	for _, opt := range options {
+		err := opt(config)
+		if err != nil {
+			panic(errors.Wrap(err, "applying solver option"))
+		}
+	}
+	return config
+}
+
+// IsDefault tells whether config can be handled by specialized solvers
+func (c *SolverConfig) IsDefault() bool {
+	return c.MoveOrder == StaticMoveOrder && c.ForcedMoves && c.Referee == LookupRefereeKind
+}
+
+func WithMoveOrder(policy MoveOrderPolicy) SolverOption {
+	return func(c *SolverConfig) error {
+		if _, err := ParseMoveOrderPolicy(string(policy)); err != nil {
+			return err
+		}
+		c.MoveOrder = policy
+		return nil
+	}
+}
+
+func WithForcedMoves(enabled bool) SolverOption {
+	return func(c *SolverConfig) error {
+		c.ForcedMoves = enabled
+		return nil
+	}
+}
+
+func WithReferee(kind RefereeKind) SolverOption {
+	return func(c *SolverConfig) error {
+		if _, err := ParseRefereeKind(string(kind)); err != nil {
+			return err
+		}
+		c.Referee = kind
+		return nil
+	}
+}
+
+// WithCache makes solver use given cache (created by a solver of the same kind and board) instead of a new one
+func WithCache(cache ICache) SolverOption {
+	return func(c *SolverConfig) error {
+		c.Cache = cache
+		return nil
+	}
+}
+
+func WithCacheLimit(entries int) SolverOption {
+	return func(c *SolverConfig) error {
+		if entries <= 0 {
+			return errors.New("cache limit should be positive")
+		}
+		c.CacheLimit = entries
+		return nil
+	}
+}
+
+func WithProgressListener(listener ProgressListener) SolverOption {
+	return func(c *SolverConfig) error {
+		c.Progress = listener
+		return nil
+	}
+}
+
+// WithLogger makes solver write log records to given logger, eg. log.Root()
+func WithLogger(logger log.Logger) SolverOption {
+	return func(c *SolverConfig) error {
+		if logger == nil {
+			return errors.New("logger is nil")
+		}
+		c.Logger = logger
+		return nil
+	}
+}
+
+// DiscardLogger creates logger dropping all records
+func DiscardLogger() log.Logger {
+	logger := log.New()
+	logger.SetHandler(log.DiscardHandler())
+	return logger
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"time"
 
 	log "github.com/igrek51/log15"
@@ -10,9 +11,19 @@ const RefreshProgressPeriod = 2 * time.Second
 const ItReportPeriodMask = 0b11111111111111111111 // modulo 2^20 (1048576) mask
 
 type IMoveSolver interface {
+	// MovesEndings solves endings of all moves, it stops on SIGINT returning nil
 	MovesEndings(board *Board) []Player
+	// MovesEndingsContext solves endings of all moves, it stops when the context is done returning its error
+	MovesEndingsContext(ctx context.Context, board *Board) ([]Player, error)
 	HasPlayerWon(board *Board, move int, y int, player Player) bool
 	SummaryVars() log.Ctx
 	Cache() ICache
 	Retrain(board *Board, maxDepth uint)
+	RetrainContext(ctx context.Context, board *Board, maxDepth uint) error
+}
+
+// IProofOrder is implemented by solvers of games where positions may repeat,
+// MovesProofOrder tells the order in which endings after each move were proved, nil if unknown
+type IProofOrder interface {
+	MovesProofOrder(board *Board) []int
 }
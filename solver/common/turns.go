@@ -0,0 +1,93 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TurnSchedule tells how many moves players make in consecutive turns, the last turn length repeats,
+// eg. [1 2] means A makes one move, then B makes two moves, then A makes two moves and so on.
+// Empty schedule means players alternate after every move.
+type TurnSchedule []int
+
+// ParseTurnSchedule reads comma separated lengths of turns, eg. "1,2"
+func ParseTurnSchedule(spec string) (TurnSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	schedule := TurnSchedule{}
+	for _, part := range strings.Split(spec, ",") {
+		moves, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing turn length %s", part)
+		}
+		if moves < 1 {
+			return nil, fmt.Errorf("turn length should be positive, got %d", moves)
+		}
+		schedule = append(schedule, moves)
+	}
+	return schedule, nil
+}
+
+// IsAlternating tells if players take turns after every single move
+func (t TurnSchedule) IsAlternating() bool {
+	for _, moves := range t {
+		if moves != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// PlayerAtPly returns player making a move number ply (counting from 0)
+func (t TurnSchedule) PlayerAtPly(ply int) Player {
+	if len(t) == 0 {
+		return Player(ply % 2)
+	}
+	player := PlayerA
+	for turn := 0; ; turn++ {
+		moves := t[len(t)-1]
+		if turn < len(t) {
+			moves = t[turn]
+		}
+		if ply < moves {
+			return player
+		}
+		ply -= moves
+		player = OppositePlayer(player)
+	}
+}
+
+// PlayersByPly lists players making consecutive moves until given number of plies
+func (t TurnSchedule) PlayersByPly(plies int) []Player {
+	players := make([]Player, plies)
+	for ply := range players {
+		players[ply] = t.PlayerAtPly(ply)
+	}
+	return players
+}
+
+// String renders schedule for file names, eg. "1-2"
+func (t TurnSchedule) String() string {
+	parts := []string{}
+	for _, moves := range t {
+		parts = append(parts, strconv.Itoa(moves))
+	}
+	return strings.Join(parts, "-")
+}
+
+func WithTurnSchedule(schedule TurnSchedule) Option {
+	return func(b *Board) error {
+		for _, moves := range schedule {
+			if moves < 1 {
+				return fmt.Errorf("turn length should be positive, got %d", moves)
+			}
+		}
+		b.Turns = schedule
+		return nil
+	}
+}
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"fmt"
+)
+
+// Variant of game rules
+type Variant string
+
+const (
+	// StandardVariant - players drop tokens only
+	StandardVariant Variant = "standard"
+	// PopOutVariant - player may also remove own token from the bottom of a column instead of dropping
+	PopOutVariant Variant = "popout"
+	// MisereVariant - completing a line loses the game
+	MisereVariant Variant = "misere"
+	// CylinderVariant - horizontal and diagonal lines wrap around from the last column to the first one
+	CylinderVariant Variant = "cylinder"
+)
+
+func ParseVariant(name string) (Variant, error) {
+	switch Variant(name) {
+	case StandardVariant, PopOutVariant, MisereVariant, CylinderVariant:
+		return Variant(name), nil
+	}
+	return "", fmt.Errorf("unknown variant: %s", name)
+}
+
+func WithVariant(variant Variant) Option {
+	return func(b *Board) error {
+		if _, err := ParseVariant(string(variant)); err != nil {
+			return err
+		}
+		b.Variant = variant
+		return nil
+	}
+}
@@ -15,14 +15,28 @@ type EndingCache struct {
 	cacheUsages   uint64
 	clears        uint64
 	depthClears   []uint64
+	logger        log.Logger
 
-	boardW  int
-	boardH  int
-	boardW1 int
-	sideW   int
+	boardW   int
+	boardH   int
+	boardW1  int
+	sideW    int
+	symmetry cacheSymmetry
 }
 
-func NewEndingCache(boardW int, boardH int) *EndingCache {
+// cacheSymmetry tells which transformations of the board lead to equivalent positions sharing the same key
+type cacheSymmetry int
+
+const (
+	mirrorSymmetry cacheSymmetry = iota
+	// rotational symmetry applies to cylindrical boards, where columns can be shifted around
+	rotationalSymmetry
+	// asymmetric board shapes can't be reflected
+	noSymmetry
+)
+
+// NewEndingCache creates cache limited to given number of entries, writing log records to the logger
+func NewEndingCache(boardW int, boardH int, sizeLimit int, logger log.Logger) *EndingCache {
 	depthCaches := make([]map[uint64]common.Player, boardW*boardH)
 	for i := uint(0); i < uint(boardW*boardH); i++ {
 		depthCaches[i] = make(map[uint64]common.Player)
@@ -31,9 +45,10 @@ func NewEndingCache(boardW int, boardH int) *EndingCache {
 	return &EndingCache{
 		depthCaches:            depthCaches,
 		depthClears:            make([]uint64, boardW*boardH),
-		maxCacheDepthSize:      common.CacheSizeLimit / (boardW * boardH),
+		maxCacheDepthSize:      sizeLimit / (boardW * boardH),
 		maxCachedDepth:         uint(boardW*boardH) - 4,
 		maxUnclearedCacheDepth: 16,
+		logger:                 logger,
 		boardW:                 boardW,
 		boardH:                 boardH,
 		boardW1:                boardW - 1,
@@ -41,8 +56,27 @@ func NewEndingCache(boardW int, boardH int) *EndingCache {
 	}
 }
 
+// NewCylinderEndingCache creates cache treating all rotations and reflections of the board as the same position
+func NewCylinderEndingCache(boardW int, boardH int, sizeLimit int, logger log.Logger) *EndingCache {
+	cache := NewEndingCache(boardW, boardH, sizeLimit, logger)
+	cache.symmetry = rotationalSymmetry
+	return cache
+}
+
+// NewBoardEndingCache creates cache making use of all symmetries of the board shape and variant
+func NewBoardEndingCache(board *common.Board, sizeLimit int, logger log.Logger) *EndingCache {
+	if board.Variant == common.CylinderVariant && board.IsRectangular() {
+		return NewCylinderEndingCache(board.W, board.H, sizeLimit, logger)
+	}
+	cache := NewEndingCache(board.W, board.H, sizeLimit, logger)
+	if !board.IsMirrorSymmetric() {
+		cache.symmetry = noSymmetry
+	}
+	return cache
+}
+
 func (s *EndingCache) Get(board *common.Board, depth uint) (ending common.Player, ok bool) {
-	ending, ok = s.depthCaches[depth][s.reflectedBoardKey(board.State)]
+	ending, ok = s.depthCaches[depth][s.boardKey(board.State)]
 	return
 }
 
@@ -53,13 +87,13 @@ func (s *EndingCache) Put(board *common.Board, depth uint, ending common.Player)
 	if s.DepthSize(depth) >= s.maxCacheDepthSize && depth > s.maxUnclearedCacheDepth {
 		s.ClearCache(depth)
 	}
-	s.depthCaches[depth][s.reflectedBoardKey(board.State)] = ending
+	s.depthCaches[depth][s.boardKey(board.State)] = ending
 	s.cachedEntries++
 	return ending
 }
 
 func (s *EndingCache) ClearCache(depth uint) {
-	log.Debug("clearing cache", log.Ctx{"depth": depth})
+	s.logger.Debug("clearing cache", log.Ctx{"depth": depth})
 	s.cachedEntries -= uint64(s.DepthSize(depth))
 	s.depthCaches[depth] = make(map[uint64]common.Player)
 	s.depthClears[depth]++
@@ -74,6 +108,24 @@ func (s *EndingCache) DepthSize(depth uint) int {
 	return len(s.depthCaches[depth])
 }
 
+func (s *EndingCache) boardKey(key common.BoardKey) uint64 {
+	switch s.symmetry {
+	case rotationalSymmetry:
+		return s.rotatedBoardKey(key)
+	case noSymmetry:
+		return s.plainBoardKey(key)
+	}
+	return s.reflectedBoardKey(key)
+}
+
+func (s *EndingCache) plainBoardKey(key common.BoardKey) uint64 {
+	plainKey := uint64(0)
+	for i := 0; i < s.boardW; i++ {
+		plainKey |= key[i] << (8 * i)
+	}
+	return plainKey
+}
+
 func (s *EndingCache) reflectedBoardKey(key common.BoardKey) uint64 {
 	leftKey := key[0]
 	rightKey := key[s.boardW1]
@@ -95,6 +147,25 @@ func (s *EndingCache) reflectedBoardKey(key common.BoardKey) uint64 {
 	return rightKey
 }
 
+// rotatedBoardKey finds the lowest key among all rotations of the board and their mirror images
+func (s *EndingCache) rotatedBoardKey(key common.BoardKey) uint64 {
+	minKey := ^uint64(0)
+	for shift := 0; shift < s.boardW; shift++ {
+		var rotatedKey, mirroredKey uint64
+		for i := 0; i < s.boardW; i++ {
+			rotatedKey |= key[(shift+i)%s.boardW] << (8 * i)
+			mirroredKey |= key[(shift+s.boardW-i)%s.boardW] << (8 * i)
+		}
+		if rotatedKey < minKey {
+			minKey = rotatedKey
+		}
+		if mirroredKey < minKey {
+			minKey = mirroredKey
+		}
+	}
+	return minKey
+}
+
 func (s *EndingCache) MaxCachedDepth() uint {
 	return s.maxCachedDepth
 }
@@ -107,3 +178,17 @@ func (s *EndingCache) SetEntry(depth int, key uint64, value common.Player) {
 	s.depthCaches[depth][key] = value
 	s.cachedEntries++
 }
+
+func (s *EndingCache) Stats() common.CacheStats {
+	depthSizes := make([]int, len(s.depthCaches))
+	for depth, depthCache := range s.depthCaches {
+		depthSizes[depth] = len(depthCache)
+	}
+	return common.CacheStats{
+		Size:        s.cachedEntries,
+		Hits:        s.cacheUsages,
+		Clears:      s.clears,
+		DepthSizes:  depthSizes,
+		DepthClears: append([]uint64{}, s.depthClears...),
+	}
+}
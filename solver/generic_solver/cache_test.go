@@ -35,7 +35,7 @@ A . B A A
 . B . . A
 A A B . A
 `)
-	cache := NewEndingCache(5, 3)
+	cache := NewEndingCache(5, 3, DefaultCacheSizeLimit, DiscardLogger())
 
 	cache.Put(boardL, 7, PlayerA)
 
@@ -47,3 +47,35 @@ A A B . A
 	assert.EqualValues(t, true, ok)
 	assert.EqualValues(t, PlayerA, end)
 }
+
+func TestRotatedKey(t *testing.T) {
+	board := ParseBoard(`
+A . . . .
+A . . B .
+A . B A A
+`)
+	rotated := ParseBoard(`
+. . A . .
+B . A . .
+A A A . B
+`)
+	mirrored := ParseBoard(`
+. . A . .
+. . A . B
+B . A A A
+`)
+	cache := NewCylinderEndingCache(5, 3, DefaultCacheSizeLimit, DiscardLogger())
+
+	cache.Put(board, 7, PlayerB)
+
+	end, ok := cache.Get(rotated, 7)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, PlayerB, end)
+
+	end, ok = cache.Get(mirrored, 7)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, PlayerB, end)
+
+	_, ok = NewEndingCache(5, 3, DefaultCacheSizeLimit, DiscardLogger()).Get(rotated, 7)
+	assert.EqualValues(t, false, ok)
+}
@@ -1,6 +1,8 @@
 package generic_solver
 
 import (
+	log "github.com/igrek51/log15"
+
 	"github.com/igrek51/connect4solver/solver/common"
 )
 
@@ -8,9 +10,11 @@ type Referee struct {
 	w         int
 	h         int
 	winStreak int
+	cylinder  bool
 
 	verticalMovesMap            []common.Player
 	binaryRowMap                []bool
+	horizontalRowMap            []bool
 	diagonalEvaluatorMap        [][]winnerEvaluator
 	counterDiagonalEvaluatorMap [][]winnerEvaluator
 	winStreak1                  int
@@ -29,12 +33,25 @@ type coordinate struct {
 	y int
 }
 
+// NewConfiguredReferee creates referee of chosen implementation
+func NewConfiguredReferee(board *common.Board, kind common.RefereeKind, logger log.Logger) common.IReferee {
+	if kind == common.BitboardRefereeKind {
+		if board.Variant == common.CylinderVariant {
+			logger.Warn("Bitboard referee doesn't support wrapping lines, using lookup referee")
+			return NewReferee(board)
+		}
+		return NewBitboardReferee(board)
+	}
+	return NewReferee(board)
+}
+
 func NewReferee(board *common.Board) *Referee {
 	s := &Referee{
 		w:          board.W,
 		h:          board.H,
 		winStreak:  board.WinStreak,
 		winStreak1: board.WinStreak - 1,
+		cylinder:   board.Variant == common.CylinderVariant,
 	}
 
 	// get all possible column layouts and pre-calculate winners for them
@@ -54,6 +71,10 @@ func NewReferee(board *common.Board) *Referee {
 		binaryRowMap[binaryRow] = s.hasWonRow(binaryRow)
 	}
 	s.binaryRowMap = binaryRowMap
+	s.horizontalRowMap = binaryRowMap
+	if s.cylinder {
+		s.horizontalRowMap = s.buildWrappedRowMap()
+	}
 
 	// build functions handling diagonal validation for each coordinate
 	s.diagonalEvaluatorMap = make([][]winnerEvaluator, board.W)
@@ -77,7 +98,11 @@ func (s *Referee) HasPlayerWon(board *common.Board, move int, y int, player comm
 }
 
 func (s *Referee) HasPlayerWonVertical(board *common.Board, move int, player common.Player) bool {
-	return s.verticalMovesMap[board.State[move]] == player
+	state := board.State[move]
+	if player == common.PlayerA { // neutral tokens are stored as A's ones
+		state |= board.Neutral[move]
+	}
+	return s.verticalMovesMap[state] == player
 }
 
 func (s *Referee) HasPlayerWonHorizontal(board *common.Board, y int, player common.Player) bool {
@@ -87,7 +112,7 @@ func (s *Referee) HasPlayerWonHorizontal(board *common.Board, y int, player comm
 			binaryRow |= 1 << x
 		}
 	}
-	return s.binaryRowMap[binaryRow]
+	return s.horizontalRowMap[binaryRow]
 }
 
 func (s *Referee) HasPlayerWonDiagonal(board *common.Board, x int, y int, player common.Player) bool {
@@ -136,7 +161,9 @@ func (s *Referee) buildDiagonalEvaluator(startX, startY int) winnerEvaluator {
 	coordinates := []coordinate{}
 	y := startY - s.winStreak1
 	for x := startX - s.winStreak1; x <= startX+s.winStreak1; x++ {
-		if x >= 0 && x < s.w && y >= 0 && y < s.h {
+		if s.cylinder {
+			coordinates = s.appendWrapped(coordinates, x, y)
+		} else if x >= 0 && x < s.w && y >= 0 && y < s.h {
 			coordinates = append(coordinates, coordinate{x: x, y: y})
 		}
 		y++
@@ -161,7 +188,9 @@ func (s *Referee) buildCounterDiagonalEvaluator(startX, startY int) winnerEvalua
 	coordinates := []coordinate{}
 	y := startY + s.winStreak1
 	for x := startX - s.winStreak1; x <= startX+s.winStreak1; x++ {
-		if x >= 0 && x < s.w && y >= 0 && y < s.h {
+		if s.cylinder {
+			coordinates = s.appendWrapped(coordinates, x, y)
+		} else if x >= 0 && x < s.w && y >= 0 && y < s.h {
 			coordinates = append(coordinates, coordinate{x: x, y: y})
 		}
 		y--
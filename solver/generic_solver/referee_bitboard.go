@@ -0,0 +1,104 @@
+package generic_solver
+
+import (
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// BitboardReferee keeps player tokens as a single binary number,
+// each column takes H+1 bits, the last one is always zero to separate columns:
+//
+//	.  .  .
+//	2  5  8
+//	1  4  7
+//	0  3  6
+//
+// Winning streak of any length is found by shifting the board along one of 4 directions.
+type BitboardReferee struct {
+	w          int
+	h          int
+	winStreak  int
+	columnBits int
+	directions [4]int
+}
+
+func NewBitboardReferee(board *common.Board) *BitboardReferee {
+	columnBits := board.H + 1
+	return &BitboardReferee{
+		w:          board.W,
+		h:          board.H,
+		winStreak:  board.WinStreak,
+		columnBits: columnBits,
+		directions: [4]int{
+			1,              // vertical
+			columnBits,     // horizontal
+			columnBits + 1, // diagonal
+			columnBits - 1, // counter-diagonal
+		},
+	}
+}
+
+// PlayerBitboard extracts cells occupied by given player
+func (r *BitboardReferee) PlayerBitboard(board *common.Board, player common.Player) uint64 {
+	var bitboard uint64
+	for x := 0; x < r.w; x++ {
+		column := board.State[x]
+		if player == common.PlayerA {
+			column = ^column
+		}
+		column &= (1 << board.StackSize(x)) - 1
+		column &= ^board.Neutral[x]
+		bitboard |= column << (x * r.columnBits)
+	}
+	return bitboard
+}
+
+func (r *BitboardReferee) HasPlayerWon(board *common.Board, move int, y int, player common.Player) bool {
+	bitboard := r.PlayerBitboard(board, player)
+	cell := uint64(1) << (move*r.columnBits + y)
+	for _, direction := range r.directions {
+		if r.streakCells(bitboard, direction)&cell != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPlayerLine checks if player has a winning streak anywhere on the board
+func (r *BitboardReferee) HasPlayerLine(board *common.Board, player common.Player) bool {
+	bitboard := r.PlayerBitboard(board, player)
+	for _, direction := range r.directions {
+		if r.streakStarts(bitboard, direction) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *BitboardReferee) HasWinner(board *common.Board) common.Player {
+	if r.HasPlayerLine(board, common.PlayerA) {
+		return common.PlayerA
+	}
+	if r.HasPlayerLine(board, common.PlayerB) {
+		return common.PlayerB
+	}
+	return common.Empty
+}
+
+// streakStarts marks the first cells of all winning streaks in given direction
+func (r *BitboardReferee) streakStarts(bitboard uint64, direction int) uint64 {
+	starts := bitboard
+	for i := 1; i < r.winStreak; i++ {
+		starts &= bitboard >> (direction * i)
+	}
+	return starts
+}
+
+// streakCells marks all cells belonging to winning streaks in given direction
+func (r *BitboardReferee) streakCells(bitboard uint64, direction int) uint64 {
+	starts := r.streakStarts(bitboard, direction)
+	cells := starts
+	for i := 1; i < r.winStreak; i++ {
+		cells |= starts << (direction * i)
+	}
+	return cells
+}
@@ -0,0 +1,92 @@
+package generic_solver
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/igrek51/connect4solver/solver/common"
+)
+
+func TestBitboardWonDiagonal(t *testing.T) {
+	board := ParseBoard(`
+	. . . . . . .
+	. . . . . . .
+	. . . . . . A
+	. . . . . A A
+	. B . B A B B
+	A A . A B B A
+	`)
+	referee := NewBitboardReferee(board)
+	assert.EqualValues(t, PlayerA, referee.HasWinner(board))
+	assert.EqualValues(t, true, referee.HasPlayerWon(board, 3, 0, PlayerA))
+	assert.EqualValues(t, true, referee.HasPlayerWon(board, 6, 3, PlayerA))
+	assert.EqualValues(t, false, referee.HasPlayerWon(board, 6, 2, PlayerA))
+	assert.EqualValues(t, false, referee.HasPlayerWon(board, 5, 1, PlayerB))
+}
+
+func TestBitboardLongStreak(t *testing.T) {
+	board := ParseBoard(`
+	. . . . . . .
+	B B B B B B .
+	A A A A A A B
+	`, WithWinStreak(7))
+	referee := NewBitboardReferee(board)
+	assert.EqualValues(t, Empty, referee.HasWinner(board))
+
+	board.Throw(6, PlayerB)
+	assert.EqualValues(t, true, referee.HasPlayerWon(board, 6, 1, PlayerB))
+	assert.EqualValues(t, false, referee.HasPlayerWon(board, 6, 1, PlayerA))
+	assert.EqualValues(t, PlayerB, referee.HasWinner(board))
+	board.Revert(6, 1)
+	board.Revert(6, 0)
+	board.Throw(6, PlayerA)
+	assert.EqualValues(t, PlayerA, referee.HasWinner(board))
+	assert.EqualValues(t, true, referee.HasPlayerWon(board, 6, 0, PlayerA))
+}
+
+// TestBitboardEquivalentToLegacy plays random games and compares both referees after each move
+func TestBitboardEquivalentToLegacy(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	for game := 0; game < 3000; game++ {
+		w := 2 + random.Intn(6)
+		h := 1 + random.Intn(6)
+		winStreak := 2 + random.Intn(6)
+		board := NewBoard(WithSize(w, h), WithWinStreak(winStreak))
+		legacy := NewReferee(board)
+		bitboard := NewBitboardReferee(board)
+
+		for board.CountMoves() < uint(w*h) {
+			player := board.NextPlayer()
+			move := random.Intn(w)
+			if !board.CanMakeMove(move) {
+				continue
+			}
+			y := board.Throw(move, player)
+
+			winner := legacy.HasWinner(board)
+			if !assert.Equal(t, winner, bitboard.HasWinner(board), board.String()) {
+				return
+			}
+			if !assert.Equal(t, winner == player, bitboard.HasPlayerWon(board, move, y, player), board.String()) {
+				return
+			}
+			if winner != Empty {
+				break
+			}
+		}
+	}
+}
+
+func TestSolverWithBitboardReferee(t *testing.T) {
+	for _, board := range []*Board{
+		NewBoard(WithSize(4, 4), WithWinStreak(4)),
+		NewBoard(WithSize(5, 4), WithWinStreak(3)),
+		NewBoard(WithSize(3, 3), WithWinStreak(2)),
+	} {
+		lookupSolver := NewMoveSolver(board, WithReferee(LookupRefereeKind))
+		bitboardSolver := NewMoveSolver(board, WithReferee(BitboardRefereeKind))
+		assert.Equal(t, lookupSolver.MovesEndings(board), bitboardSolver.MovesEndings(board))
+	}
+}
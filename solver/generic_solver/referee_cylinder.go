@@ -0,0 +1,24 @@
+package generic_solver
+
+// buildWrappedRowMap pre-calculates winners of horizontal rows wrapping around the cylinder.
+// Row is doubled, so the streak crossing the edge is found as a regular one.
+// Streak longer than board width would use some cells twice, so it can't be formed horizontally.
+func (s *Referee) buildWrappedRowMap() []bool {
+	wrappedRowMap := make([]bool, len(s.binaryRowMap))
+	if s.winStreak > s.w {
+		return wrappedRowMap
+	}
+	for binaryRow := uint64(0); binaryRow < 1<<s.w; binaryRow++ {
+		wrappedRowMap[binaryRow] = s.hasWonRow(binaryRow | binaryRow<<s.w)
+	}
+	return wrappedRowMap
+}
+
+// appendWrapped adds diagonal coordinate, crossing the side edges of the cylinder
+func (s *Referee) appendWrapped(coordinates []coordinate, x int, y int) []coordinate {
+	if y < 0 || y >= s.h {
+		return coordinates
+	}
+	x = (x%s.w + s.w) % s.w
+	return append(coordinates, coordinate{x: x, y: y})
+}
@@ -5,6 +5,9 @@ import (
 )
 
 func (s *Referee) HasWinner(board *common.Board) common.Player {
+	if s.cylinder || !board.IsRectangular() {
+		return s.hasWinnerOnAnyCell(board)
+	}
 	s.winner = s.checkVertical(board)
 	if s.winner != common.Empty {
 		return s.winner
@@ -23,6 +26,20 @@ func (s *Referee) HasWinner(board *common.Board) common.Player {
 	return common.Empty
 }
 
+// hasWinnerOnAnyCell checks lines going through every token,
+// including the ones crossing the side edges or interrupted by neutral tokens
+func (s *Referee) hasWinnerOnAnyCell(board *common.Board) common.Player {
+	for x := 0; x < s.w; x++ {
+		for y := 0; y < board.StackSize(x); y++ {
+			player := board.GetCell(x, y)
+			if player != common.Neutral && s.HasPlayerWon(board, x, y, player) {
+				return player
+			}
+		}
+	}
+	return common.Empty
+}
+
 func (s *Referee) checkVertical(board *common.Board) common.Player {
 	for x := 0; x < board.W; x++ {
 		s.winner = s.checkColumnSequence(board, board.State[x], board.StackSize(x))
@@ -171,3 +171,66 @@ func TestPlayerWonVerticalOnMove(t *testing.T) {
 	assert.EqualValues(t, true, referee.HasPlayerWonVertical(board, 0, PlayerA))
 	assert.EqualValues(t, false, referee.HasPlayerWonVertical(board, 0, PlayerB))
 }
+
+func TestWonWrappedHorizontal(t *testing.T) {
+	board := ParseBoard(`
+	. . . . . . .
+	. . . . . . .
+	. . . . . . .
+	. . . . . . .
+	B B . . . . B
+	A A . . B A A
+	`, WithVariant(CylinderVariant))
+	referee := NewReferee(board)
+	assert.EqualValues(t, PlayerA, referee.HasWinner(board))
+	assert.EqualValues(t, true, referee.HasPlayerWon(board, 0, 0, PlayerA))
+	assert.EqualValues(t, true, referee.HasPlayerWon(board, 5, 0, PlayerA))
+	assert.EqualValues(t, false, referee.HasPlayerWon(board, 1, 1, PlayerB))
+
+	board.Variant = StandardVariant
+	assert.EqualValues(t, Empty, NewReferee(board).HasWinner(board))
+}
+
+func TestWonWrappedDiagonal(t *testing.T) {
+	board := ParseBoard(`
+	. . . . . . .
+	. . . . . . .
+	. A . . . . .
+	A B . . . . .
+	B A . . . . A
+	A B . . . A B
+	`, WithVariant(CylinderVariant))
+	referee := NewReferee(board)
+	assert.EqualValues(t, PlayerA, referee.HasWinner(board))
+	assert.EqualValues(t, true, referee.HasPlayerWon(board, 1, 3, PlayerA))
+	assert.EqualValues(t, true, referee.HasPlayerWon(board, 6, 1, PlayerA))
+
+	board.Variant = StandardVariant
+	assert.EqualValues(t, Empty, NewReferee(board).HasWinner(board))
+}
+
+func TestWrappedStreakLongerThanWidth(t *testing.T) {
+	board := ParseBoard(`
+	. . .
+	B B .
+	A A A
+	`, WithWinStreak(4), WithVariant(CylinderVariant))
+	referee := NewReferee(board)
+	assert.EqualValues(t, Empty, referee.HasWinner(board))
+	assert.EqualValues(t, false, referee.HasPlayerWon(board, 2, 0, PlayerA))
+}
+
+func TestNeutralTokenBreaksLine(t *testing.T) {
+	board := ParseBoard(`
+	. . . . .
+	. A . . .
+	. X . . .
+	. A . . .
+	A A X A A
+	`)
+	for _, referee := range []IReferee{NewReferee(board), NewBitboardReferee(board)} {
+		assert.EqualValues(t, Empty, referee.HasWinner(board))
+		assert.EqualValues(t, false, referee.HasPlayerWon(board, 1, 3, PlayerA))
+		assert.EqualValues(t, false, referee.HasPlayerWon(board, 4, 0, PlayerA))
+	}
+}
@@ -1,32 +1,37 @@
 package generic_solver
 
 import (
-	"os/signal"
+	"context"
 	"time"
 
-	log "github.com/igrek51/log15"
-	"github.com/pkg/errors"
-
 	"github.com/igrek51/connect4solver/solver/common"
 )
 
+// Retrain solves the board again without using cached endings up to maxDepth, it stops on SIGINT
 func (s *MoveSolver) Retrain(board *common.Board, maxDepth uint) {
+	ctx, stop := common.InterruptContext(context.Background())
+	defer stop()
+	_ = s.RetrainContext(ctx, board, maxDepth)
+}
+
+// RetrainContext solves the board again without using cached endings up to maxDepth,
+// it stops when the context is done returning its error
+func (s *MoveSolver) RetrainContext(ctx context.Context, board *common.Board, maxDepth uint) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	defer func() {
 		if r := recover(); r != nil {
-			err, ok := r.(error)
-			if !ok || !errors.Is(err, common.InterruptError) {
-				panic(r)
-			}
-			log.Debug("Interrupted")
+			err = common.InterruptCause(ctx, r, s.logger)
 		}
 	}()
-	interruptChannel := common.HandleInterrupt(s)
+	s.ctx = ctx
+	defer func() { s.ctx = context.Background() }()
 
 	s.startTime = time.Now()
 	s.lastBoardPrintTime = time.Now()
 	s.firstProgress = 0
 	s.iterations = 0
-	s.interrupt = false
 	s.retrainMaxDepth = maxDepth
 
 	depth := board.CountMoves()
@@ -40,8 +45,7 @@ func (s *MoveSolver) Retrain(board *common.Board, maxDepth uint) {
 			s.retrainEndingOnMove(board, player, move, progressStart, progressEnd, depth)
 		}
 	}
-
-	signal.Stop(interruptChannel)
+	return nil
 }
 
 // solve board without short-circuit features
@@ -69,14 +73,14 @@ func (s *MoveSolver) retrainEndingOnMove(
 	s.reportCycle(board, progressStart)
 
 	if s.referee.HasPlayerWon(board, move, y, player) {
-		return player
+		return s.rules.LineWinner(player)
 	}
 	if depth == s.tieDepth { // No more moves - Tie
 		return common.Empty
 	}
 
 	// solve further possible moves of nextPlayer, at least one possible move is guaranteed
-	nextPlayer := common.OppositePlayer(player)
+	nextPlayer := s.plyPlayers[depth+1]
 	wins := 0
 	ties := 0
 	var moveEnding common.Player
@@ -108,6 +112,6 @@ func (s *MoveSolver) retrainEndingOnMove(
 	} else if ties > 0 {
 		return s.cache.Put(board, depth, common.Empty)
 	} else {
-		return s.cache.Put(board, depth, player)
+		return s.cache.Put(board, depth, common.OppositePlayer(nextPlayer))
 	}
 }
@@ -1,8 +1,11 @@
 package generic_solver
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/igrek51/connect4solver/solver/common"
 	"github.com/stretchr/testify/assert"
@@ -125,3 +128,225 @@ func BenchmarkMoveSolver4x4(b *testing.B) {
 		b.StopTimer()
 	}
 }
+
+func TestThreatMoveOrderingPrefersWinningMove(t *testing.T) {
+	board := ParseBoard(`
+	.....
+	.....
+	AA...
+	BBB..
+	`)
+	ordering := NewThreatMoveOrdering(board, NewReferee(board))
+	assert.Equal(t, []int{3}, ordering.OrderedMoves(board, PlayerB, board.CountMoves()))
+}
+
+func TestThreatMoveOrderingSkipsMovesHandingWin(t *testing.T) {
+	board := ParseBoard(`
+	.....
+	A.AA.
+	B.AB.
+	ABABA
+	`)
+	ordering := NewThreatMoveOrdering(board, NewReferee(board))
+	moves := ordering.OrderedMoves(board, PlayerB, board.CountMoves())
+	assert.NotContains(t, moves, 1)
+	assert.ElementsMatch(t, []int{0, 2, 3, 4}, moves)
+}
+
+func TestThreatMoveOrderingSameEndings(t *testing.T) {
+	for _, board := range []*Board{
+		NewBoard(WithSize(4, 4), WithWinStreak(4)),
+		NewBoard(WithSize(4, 4), WithWinStreak(3)),
+		NewBoard(WithSize(5, 4), WithWinStreak(3)),
+		NewBoard(WithSize(3, 3), WithWinStreak(2)),
+	} {
+		staticSolver := NewMoveSolver(board, WithMoveOrder(StaticMoveOrder))
+		threatSolver := NewMoveSolver(board, WithMoveOrder(ThreatMoveOrder))
+		assert.Equal(t, staticSolver.MovesEndings(board), threatSolver.MovesEndings(board))
+	}
+}
+
+func BenchmarkMoveSolver4x4ThreatOrder(b *testing.B) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(4))
+	b.ResetTimer()
+	b.StopTimer()
+	for i := 0; i < b.N; i++ {
+		solver := NewMoveSolver(board, WithMoveOrder(ThreatMoveOrder))
+		b.StartTimer()
+		solver.MovesEndings(board)
+		b.StopTimer()
+	}
+}
+
+func TestForcedMovesReduceIterations(t *testing.T) {
+	// positions of the tests above, the ones decided by the first move can't be reduced
+	for _, testCase := range []struct {
+		name    string
+		board   *Board
+		reduced bool
+	}{
+		{"BestResultSimplest4", ParseBoard(`
+		....
+		ABAB
+		ABAB
+		ABAB
+		`), true},
+		{"BestResultSimpleTie", ParseBoard(`
+		..
+		AB
+		AB
+		AB
+		`), false},
+		{"BestResult3x3", NewBoard(WithSize(3, 3), WithWinStreak(3)), true},
+		{"BestResult3x3Unfair", NewBoard(WithSize(3, 3), WithWinStreak(2)), true},
+		{"BestResult2x2Unfair", NewBoard(WithSize(2, 2), WithWinStreak(2)), true},
+		{"CachedResultsCount", NewBoard(WithSize(3, 2), WithWinStreak(3)), true},
+		{"EndWithLastMove", ParseBoard(`
+		.BBB
+		BBAA
+		AABA
+		ABAA
+		`), false},
+		{"MoveSolver4x4", NewBoard(WithSize(4, 4), WithWinStreak(4)), true},
+	} {
+		plainSolver := NewMoveSolver(testCase.board, WithForcedMoves(false))
+		forcedSolver := NewMoveSolver(testCase.board, WithForcedMoves(true))
+
+		assert.Equal(t, plainSolver.MovesEndings(testCase.board), forcedSolver.MovesEndings(testCase.board), testCase.name)
+		message := fmt.Sprintf("%s: iterations without forced moves: %d, with forced moves: %d",
+			testCase.name, plainSolver.iterations, forcedSolver.iterations)
+		if testCase.reduced {
+			assert.Less(t, forcedSolver.iterations, plainSolver.iterations, message)
+		} else {
+			assert.LessOrEqual(t, forcedSolver.iterations, plainSolver.iterations, message)
+		}
+	}
+}
+
+func TestDoubleThreatIsLost(t *testing.T) {
+	board := ParseBoard(`
+	.....
+	.....
+	.B...
+	.AA..
+	BABAB
+	`)
+	solver := NewMoveSolver(board)
+	assert.Equal(t, PlayerA, BestEndingOnMove(solver, board, PlayerA, 3))
+	assert.EqualValues(t, 1, solver.iterations)
+}
+
+func BenchmarkMoveSolver4x4BitboardReferee(b *testing.B) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(4))
+	b.ResetTimer()
+	b.StopTimer()
+	for i := 0; i < b.N; i++ {
+		solver := NewMoveSolver(board, WithReferee(BitboardRefereeKind))
+		b.StartTimer()
+		solver.MovesEndings(board)
+		b.StopTimer()
+	}
+}
+
+func TestMisereCompletingLineLoses(t *testing.T) {
+	board := ParseBoard(`
+	....
+	ABAB
+	ABAB
+	ABAB
+	`, WithVariant(MisereVariant))
+	solver := NewMoveSolver(board)
+
+	assert.Equal(t, PlayerB,
+		BestEndingOnMove(solver, board, PlayerA, 0))
+	assert.Equal(t, PlayerB,
+		BestEndingOnMove(solver, board, PlayerA, 2))
+}
+
+func TestMisereSmallBoards(t *testing.T) {
+	board := NewBoard(WithSize(3, 3), WithWinStreak(3), WithVariant(MisereVariant))
+	endings := NewMoveSolver(board, WithMoveOrder(ThreatMoveOrder)).MovesEndings(board)
+	assert.Equal(t, []Player{PlayerA, PlayerA, PlayerA}, endings)
+
+	board = NewBoard(WithSize(4, 3), WithWinStreak(3), WithVariant(MisereVariant))
+	endings = NewMoveSolver(board).MovesEndings(board)
+	assert.Equal(t, []Player{PlayerB, PlayerB, PlayerB, PlayerB}, endings)
+}
+
+func TestCylinderSymmetricCache(t *testing.T) {
+	board := NewBoard(WithSize(5, 4), WithWinStreak(4), WithVariant(CylinderVariant))
+	solver := NewMoveSolver(board)
+	endings := solver.MovesEndings(board)
+
+	plainSolver := NewMoveSolver(board)
+	plainSolver.cache = NewEndingCache(board.W, board.H, DefaultCacheSizeLimit, DiscardLogger())
+	assert.Equal(t, endings, plainSolver.MovesEndings(board))
+	assert.Less(t, solver.cache.Size(), plainSolver.cache.Size())
+
+	board.Variant = StandardVariant
+	assert.NotEqual(t, endings, NewMoveSolver(board).MovesEndings(board))
+}
+
+func TestTurnScheduleDoubleMove(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3), WithTurnSchedule(TurnSchedule{1, 2}))
+	board.ApplyMoves("10322")
+	assert.Equal(t, PlayerB, board.NextPlayer())
+
+	// B makes two moves in a row, so it completes a line before A gets a chance to do it
+	endings := NewMoveSolver(board).MovesEndings(board)
+	assert.Equal(t, PlayerB, endings[0])
+
+	board.Turns = nil
+	endings = NewMoveSolver(board).MovesEndings(board)
+	assert.Equal(t, PlayerA, endings[0])
+}
+
+func TestSolveShapedBoard(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3))
+	endings := NewMoveSolver(board).MovesEndings(board)
+
+	// missing column is the same as a narrower board
+	shapedBoard := NewBoard(WithColumnHeights(4, 4, 4, 4, 0), WithWinStreak(3))
+	shapedEndings := NewMoveSolver(shapedBoard).MovesEndings(shapedBoard)
+	assert.Equal(t, append(endings, NoMove), shapedEndings)
+
+	// column filled with neutral tokens is the same as the missing one
+	neutralBoard := NewBoard(WithLayout("....X/....X/....X/....X"), WithWinStreak(3))
+	assert.Equal(t, shapedEndings, NewMoveSolver(neutralBoard).MovesEndings(neutralBoard))
+}
+
+func TestMovesEndingsContextCancelled(t *testing.T) {
+	board := NewBoard(WithSize(3, 3), WithWinStreak(3))
+	solver := NewMoveSolver(board)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	endings, err := solver.MovesEndingsContext(ctx, board)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, endings)
+	assert.ErrorIs(t, solver.RetrainContext(ctx, board, 1), context.Canceled)
+
+	endings, err = solver.MovesEndingsContext(context.Background(), board)
+	assert.NoError(t, err)
+	assert.Equal(t, []Player{Empty, Empty, Empty}, endings)
+}
+
+func TestConcurrentSolvesWithDeadline(t *testing.T) {
+	wg := sync.WaitGroup{}
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			board := NewBoard(WithSize(7, 6))
+			solver := NewMoveSolver(board)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_, errs[i] = solver.MovesEndingsContext(ctx, board)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+}
@@ -10,11 +10,13 @@ import (
 )
 
 func (s *MoveSolver) reportCycle(board *common.Board, progressStart float64) {
-	if s.iterations&common.ItReportPeriodMask == 0 && time.Since(s.lastBoardPrintTime) >= common.RefreshProgressPeriod {
-		s.ReportStatus(board, progressStart)
-		if s.interrupt {
+	if s.iterations&common.ItReportPeriodMask == 0 {
+		if s.ctx.Err() != nil {
 			panic(common.InterruptError)
 		}
+		if time.Since(s.lastBoardPrintTime) >= common.RefreshProgressPeriod {
+			s.ReportStatus(board, progressStart)
+		}
 	}
 }
 
@@ -31,8 +33,8 @@ func (s *MoveSolver) ReportStatus(
 	if progress > s.firstProgress && duration > 0 {
 		eta = time.Duration((1-progress)*100/(progress-s.firstProgress)) * (duration - common.RefreshProgressPeriod) / 100
 	}
-	iterationsPerSec := common.BigintSeparated(s.iterations / uint64(duration/time.Second))
-	instIterationsPerSec := common.BigintSeparated((s.iterations - s.lastIterations) / uint64(instDuration/time.Second))
+	iterationsPerSec := s.iterations / uint64(duration/time.Second)
+	instIterationsPerSec := (s.iterations - s.lastIterations) / uint64(instDuration/time.Second)
 	progressPerSec := (progress - s.lastProgress) / float64(instDuration/time.Second)
 	s.lastIterations = s.iterations
 	s.lastBoardPrintTime = time.Now()
@@ -43,7 +45,7 @@ func (s *MoveSolver) ReportStatus(
 		firstCaches = append(firstCaches, s.Cache().DepthSize(d))
 	}
 
-	log.Debug("Currently considered board", log.Ctx{
+	s.logger.Debug("Currently considered board", log.Ctx{
 		"cacheSize":         common.BigintSeparated(s.cache.Size()),
 		"iterations":        common.BigintSeparated(s.iterations),
 		"cacheClears":       common.BigintSeparated(s.cache.clears),
@@ -51,13 +53,19 @@ func (s *MoveSolver) ReportStatus(
 		"progress":          fmt.Sprintf("%v", progress),
 		"progressPerSec":    fmt.Sprintf("%v", progressPerSec),
 		"eta":               eta.Truncate(time.Second),
-		"itsAvg":            iterationsPerSec,
-		"its":               instIterationsPerSec,
+		"itsAvg":            common.BigintSeparated(iterationsPerSec),
+		"its":               common.BigintSeparated(instIterationsPerSec),
 		"firstCacheLen":     firstCaches,
 	})
-	fmt.Println(board.String())
-	if s.progressBar != nil {
-		s.progressBar.Set(int(progress * common.ProgressBarResolution))
+	if s.progress != nil {
+		s.progress.OnProgress(common.Progress{
+			Fraction:         progress,
+			ETA:              eta,
+			Iterations:       s.iterations,
+			IterationsPerSec: instIterationsPerSec,
+			Cache:            s.cache.Stats(),
+			Board:            board.Clone(),
+		})
 	}
 }
 
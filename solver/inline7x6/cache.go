@@ -15,6 +15,7 @@ type EndingCache struct {
 	cacheUsages   uint64
 	clears        uint64
 	depthClears   []uint64
+	logger        log.Logger
 
 	boardW  int
 	boardH  int
@@ -22,7 +23,8 @@ type EndingCache struct {
 	sideW   int
 }
 
-func NewEndingCache(boardW int, boardH int) *EndingCache {
+// NewEndingCache creates cache limited to given number of entries, writing log records to the logger
+func NewEndingCache(boardW int, boardH int, sizeLimit int, logger log.Logger) *EndingCache {
 	depthCaches := make([]map[uint64]common.Player, boardW*boardH)
 	depthClears := make([]uint64, boardW*boardH)
 	for i := uint(0); i < uint(boardW*boardH); i++ {
@@ -32,9 +34,10 @@ func NewEndingCache(boardW int, boardH int) *EndingCache {
 	return &EndingCache{
 		depthCaches:            depthCaches,
 		depthClears:            depthClears,
-		maxCacheDepthSize:      common.CacheSizeLimit / (boardW * boardH),
+		maxCacheDepthSize:      sizeLimit / (boardW * boardH),
 		maxCachedDepth:         38,
 		maxUnclearedCacheDepth: 16,
+		logger:                 logger,
 		boardW:                 boardW,
 		boardH:                 boardH,
 		boardW1:                boardW - 1,
@@ -60,7 +63,7 @@ func (s *EndingCache) Put(board *common.Board, depth uint, ending common.Player)
 }
 
 func (s *EndingCache) ClearCache(depth uint) {
-	log.Debug("clearing cache", log.Ctx{"depth": depth})
+	s.logger.Debug("clearing cache", log.Ctx{"depth": depth})
 	s.cachedEntries -= uint64(len(s.depthCaches[depth]))
 	s.depthCaches[depth] = make(map[uint64]common.Player)
 	s.depthClears[depth]++
@@ -98,3 +101,17 @@ func (s *EndingCache) SetEntry(depth int, key uint64, value common.Player) {
 	s.depthCaches[depth][key] = value
 	s.cachedEntries++
 }
+
+func (s *EndingCache) Stats() common.CacheStats {
+	depthSizes := make([]int, len(s.depthCaches))
+	for depth, depthCache := range s.depthCaches {
+		depthSizes[depth] = len(depthCache)
+	}
+	return common.CacheStats{
+		Size:        s.cachedEntries,
+		Hits:        s.cacheUsages,
+		Clears:      s.clears,
+		DepthSizes:  depthSizes,
+		DepthClears: append([]uint64{}, s.depthClears...),
+	}
+}
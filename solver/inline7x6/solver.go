@@ -1,42 +1,50 @@
 package inline7x6
 
 import (
-	"os/signal"
+	"context"
 	"time"
 
 	log "github.com/igrek51/log15"
 	"github.com/pkg/errors"
-	"github.com/schollz/progressbar/v3"
 
 	"github.com/igrek51/connect4solver/solver/common"
 )
 
 type MoveSolver struct {
-	cache      *EndingCache
-	referee    *Referee
-	movesOrder []int
-	interrupt  bool
-	W          int
-	H          int
-	tieDepth   uint
+	cache       *EndingCache
+	referee     *Referee
+	rules       *common.Rules
+	plyPlayers  []common.Player
+	movesOrder  []int
+	ctx         context.Context
+	forcedMoves bool
+	W           int
+	H           int
+	tieDepth    uint
 
 	startTime          time.Time
 	lastBoardPrintTime time.Time
 	firstProgress      float64
 	lastProgress       float64
-	progressBar        *progressbar.ProgressBar
+	progress           common.ProgressListener
+	logger             log.Logger
 	iterations         uint64
 	lastIterations     uint64
 	retrainMaxDepth    uint
 }
 
-func NewMoveSolver(board *common.Board) *MoveSolver {
+// NewMoveSolver creates solver optimized for 7x6 board, only cache options apply to it
+func NewMoveSolver(board *common.Board, options ...common.SolverOption) *MoveSolver {
+	config := common.NewSolverConfig(options...)
 	movesOrder := common.CalculateMovesOrder(board)
-	cache := NewEndingCache(board.W, board.H)
-	log.Debug("Solver configured", log.Ctx{
+	cache := configuredCache(board, config)
+	rules := common.NewRules(board.Variant)
+	config.Logger.Debug("Solver configured", log.Ctx{
 		"boardWidth":        board.W,
 		"boardHeight":       board.H,
 		"winStreak":         board.WinStreak,
+		"variant":           board.Variant,
+		"turns":             board.Turns,
 		"movesOrder":        movesOrder,
 		"maxCacheDepth":     cache.maxCachedDepth,
 		"maxCacheDepthSize": cache.maxCacheDepthSize,
@@ -46,33 +54,61 @@ func NewMoveSolver(board *common.Board) *MoveSolver {
 		H:                  board.H,
 		cache:              cache,
 		referee:            NewReferee(board),
+		rules:              rules,
+		plyPlayers:         board.Turns.PlayersByPly(board.W*board.H + 1),
+		forcedMoves:        rules.LineWins() && board.Turns.IsAlternating(),
 		movesOrder:         movesOrder,
 		lastBoardPrintTime: time.Now(),
 		startTime:          time.Now(),
-		progressBar:        common.NewProgressBar(),
-		interrupt:          false,
+		progress:           config.Progress,
+		logger:             config.Logger,
+		ctx:                context.Background(),
 		tieDepth:           uint(board.W*board.H - 1),
 	}
 }
 
-func (s *MoveSolver) MovesEndings(board *common.Board) (endings []common.Player) {
+// configuredCache reuses the cache injected in config or creates a new one
+func configuredCache(board *common.Board, config *common.SolverConfig) *EndingCache {
+	if config.Cache == nil {
+		return NewEndingCache(board.W, board.H, config.CacheLimit, config.Logger)
+	}
+	cache, ok := config.Cache.(*EndingCache)
+	if !ok {
+		panic(errors.New("injected cache doesn't belong to inline solver"))
+	}
+	return cache
+}
+
+// MovesEndings solves endings of all moves, it stops on SIGINT returning nil
+func (s *MoveSolver) MovesEndings(board *common.Board) []common.Player {
+	ctx, stop := common.InterruptContext(context.Background())
+	defer stop()
+	endings, err := s.MovesEndingsContext(ctx, board)
+	if err != nil {
+		return nil
+	}
+	return endings
+}
+
+// MovesEndingsContext solves endings of all moves, it stops when the context is done returning its error
+func (s *MoveSolver) MovesEndingsContext(
+	ctx context.Context, board *common.Board,
+) (endings []common.Player, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	defer func() {
 		if r := recover(); r != nil {
-			err, ok := r.(error)
-			if !ok || !errors.Is(err, common.InterruptError) {
-				panic(r)
-			}
-			log.Debug("Interrupted")
-			endings = nil
+			endings, err = nil, common.InterruptCause(ctx, r, s.logger)
 		}
 	}()
-	interruptChannel := common.HandleInterrupt(s)
+	s.ctx = ctx
+	defer func() { s.ctx = context.Background() }()
 
 	s.startTime = time.Now()
 	s.lastBoardPrintTime = time.Now()
 	s.firstProgress = 0
 	s.iterations = 0
-	s.interrupt = false
 	endings = make([]common.Player, board.W)
 	player := board.NextPlayer()
 	depth := board.CountMoves()
@@ -89,8 +125,7 @@ func (s *MoveSolver) MovesEndings(board *common.Board) (endings []common.Player)
 		}
 	}
 
-	signal.Stop(interruptChannel)
-	return endings
+	return endings, nil
 }
 
 // bestEndingOnMove finds best ending on given next move
@@ -118,14 +153,25 @@ func (s *MoveSolver) bestEndingOnMove(
 	s.reportCycle(board, progressStart)
 
 	if s.referee.HasPlayerWon(board, move, y, player) {
-		return player
+		return s.rules.LineWinner(player)
 	}
 	if depth == 41 { // No more moves - Tie
 		return common.Empty
 	}
 
 	// solve further possible moves of nextPlayer, at least one possible move is guaranteed
-	nextPlayer := common.OppositePlayer(player)
+	nextPlayer := s.plyPlayers[depth+1]
+	if s.forcedMoves {
+		forcedMove, forcedEnding := s.forcedSituation(board, player, nextPlayer)
+		if forcedEnding != common.NoMove {
+			return s.cache.Put(board, depth, forcedEnding)
+		}
+		if forcedMove >= 0 { // the only move not losing immediately
+			moveEnding := s.bestEndingOnMove(board, nextPlayer, forcedMove, progressStart, progressEnd, depth+1)
+			return s.cache.Put(board, depth, moveEnding)
+		}
+	}
+
 	ties := 0
 	for moveIndex := 0; moveIndex < 7; moveIndex++ {
 		if board.CanMakeMove(s.movesOrder[moveIndex]) {
@@ -143,20 +189,52 @@ func (s *MoveSolver) bestEndingOnMove(
 			}
 		}
 	}
-	// player favors Tie over Lose
+	// nextPlayer favors Tie over Lose
 	if ties > 0 {
 		return s.cache.Put(board, depth, common.Empty)
 	} else {
-		return s.cache.Put(board, depth, player)
+		return s.cache.Put(board, depth, common.OppositePlayer(nextPlayer))
 	}
 }
 
-func (s *MoveSolver) HasPlayerWon(board *common.Board, move int, y int, player common.Player) bool {
-	return s.referee.HasPlayerWon(board, move, y, player)
+// forcedSituation detects immediate wins of nextPlayer (returning the ending),
+// single threat of the opponent that has to be blocked (returning forced move)
+// or double threat of the opponent (returning lost ending).
+func (s *MoveSolver) forcedSituation(
+	board *common.Board,
+	player common.Player,
+	nextPlayer common.Player,
+) (forcedMove int, ending common.Player) {
+	for x := 0; x < 7; x++ {
+		if board.CanMakeMove(x) {
+			y := board.Throw(x, nextPlayer)
+			won := s.referee.HasPlayerWon(board, x, y, nextPlayer)
+			board.Revert(x, y)
+			if won {
+				return x, nextPlayer
+			}
+		}
+	}
+
+	forcedMove = -1
+	for x := 0; x < 7; x++ {
+		if board.CanMakeMove(x) {
+			y := board.Throw(x, player)
+			threat := s.referee.HasPlayerWon(board, x, y, player)
+			board.Revert(x, y)
+			if threat {
+				if forcedMove >= 0 { // can't block two threats at once
+					return -1, player
+				}
+				forcedMove = x
+			}
+		}
+	}
+	return forcedMove, common.NoMove
 }
 
-func (s *MoveSolver) Interrupt() {
-	s.interrupt = true
+func (s *MoveSolver) HasPlayerWon(board *common.Board, move int, y int, player common.Player) bool {
+	return s.referee.HasPlayerWon(board, move, y, player)
 }
 
 func (s *MoveSolver) Cache() common.ICache {
@@ -0,0 +1,92 @@
+package solver
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	log "github.com/igrek51/log15"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// LogFormat tells how log records are written
+type LogFormat string
+
+const (
+	// TextLogFormat is human readable when colors are enabled on stdout and logfmt otherwise
+	TextLogFormat LogFormat = "text"
+	// JsonLogFormat writes records as JSON lines
+	JsonLogFormat LogFormat = "json"
+)
+
+var colorCodes = regexp.MustCompile("\u001b\\[[0-9;]*m")
+
+// logsToStdout tells if logs are mixed with the terminal output
+var logsToStdout = true
+
+// SetupLogging configures the root logger with the minimal level, format and output file (stdout if empty).
+// Players and endings in log fields are rendered plain, by their names.
+func SetupLogging(level string, format LogFormat, file string) error {
+	lvl, err := log.LvlFromString(level)
+	if err != nil {
+		return fmt.Errorf("unknown log level: %s", level)
+	}
+	colored := file == "" && common.Display.Color
+	var formatter log.Format
+	switch format {
+	case TextLogFormat:
+		formatter = log.LogfmtFormat()
+		if colored {
+			formatter = log.TerminalFormat()
+		}
+	case JsonLogFormat:
+		formatter = log.JsonFormat()
+	default:
+		return fmt.Errorf("unknown log format: %s", format)
+	}
+
+	handler := log.StreamHandler(os.Stdout, formatter)
+	if file != "" {
+		handler, err = log.FileHandler(file, formatter)
+		if err != nil {
+			return err
+		}
+	}
+	plainMessage := !colored || format != TextLogFormat
+	log.Root().SetHandler(log.LvlFilterHandler(lvl, plainHandler(handler, plainMessage)))
+	logsToStdout = file == ""
+	return nil
+}
+
+// plainHandler renders lists of players and endings in log fields by their names,
+// the message is stripped of color codes, unless it goes to a colored terminal
+func plainHandler(handler log.Handler, plainMessage bool) log.Handler {
+	return log.FuncHandler(func(r *log.Record) error {
+		if plainMessage {
+			r.Msg = colorCodes.ReplaceAllString(r.Msg, "")
+		}
+		for i := 1; i < len(r.Ctx); i += 2 {
+			r.Ctx[i] = plainValue(r.Ctx[i])
+		}
+		return handler.Log(r)
+	})
+}
+
+func plainValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []common.Player:
+		names := make([]string, len(v))
+		for i, player := range v {
+			names[i] = player.String()
+		}
+		return names
+	case []common.GameEnding:
+		names := make([]string, len(v))
+		for i, ending := range v {
+			names[i] = ending.String()
+		}
+		return names
+	}
+	return value
+}
@@ -0,0 +1,46 @@
+package solver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/igrek51/log15"
+
+	. "github.com/igrek51/connect4solver/solver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonLogsArePlain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "c4solver")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer log.Root().SetHandler(log.Root().GetHandler())
+	file := filepath.Join(dir, "solver.log")
+
+	assert.NoError(t, SetupLogging("info", JsonLogFormat, file))
+	log.Debug("Skipped")
+	log.Info("Player "+(&Renderer{Theme: AsciiTheme, Color: true}).Player(PlayerA)+" won", log.Ctx{
+		"winner":  PlayerB,
+		"endings": []Player{PlayerA, Empty, NoMove},
+		"ending":  Win,
+		"cached":  []GameEnding{Lose, NoEnding},
+	})
+
+	content, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "Skipped")
+	assert.NotContains(t, string(content), "\u001b")
+	assert.Contains(t, string(content), `"msg":"Player A won"`)
+	assert.Contains(t, string(content), `"winner":"B"`)
+	assert.Contains(t, string(content), `"endings":["A",".","-"]`)
+	assert.Contains(t, string(content), `"ending":"Win"`)
+	assert.Contains(t, string(content), `"cached":["Lose","None"]`)
+}
+
+func TestSetupLoggingInvalidArguments(t *testing.T) {
+	defer log.Root().SetHandler(log.Root().GetHandler())
+	assert.Error(t, SetupLogging("verbose", TextLogFormat, ""))
+	assert.Error(t, SetupLogging("info", "xml", ""))
+}
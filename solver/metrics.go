@@ -0,0 +1,100 @@
+package solver
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// MetricsCollector keeps the latest progress of solving and serves it in Prometheus text format
+type MetricsCollector struct {
+	mu        sync.Mutex
+	progress  common.Progress
+	startTime time.Time
+}
+
+func NewMetricsCollector() *MetricsCollector {
+	return &MetricsCollector{startTime: time.Now()}
+}
+
+// OnProgress stores the snapshot, so that scraping doesn't touch the cache used by the solving goroutine
+func (m *MetricsCollector) OnProgress(progress common.Progress) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	progress.Board = nil
+	m.progress = progress
+}
+
+func (m *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteMetrics(w)
+}
+
+// WriteMetrics writes the latest progress and memory stats in Prometheus text exposition format
+func (m *MetricsCollector) WriteMetrics(w io.Writer) {
+	m.mu.Lock()
+	progress := m.progress
+	m.mu.Unlock()
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	writeMetric(w, "c4solver_uptime_seconds", "gauge", "Time since the start of the solver",
+		time.Since(m.startTime).Seconds())
+	// iterations start over with each solved position, so they don't make a counter
+	writeMetric(w, "c4solver_iterations", "gauge", "Number of considered moves in the current solve", progress.Iterations)
+	writeMetric(w, "c4solver_iterations_per_second", "gauge", "Recent number of considered moves per second",
+		progress.IterationsPerSec)
+	writeMetric(w, "c4solver_progress_ratio", "gauge", "Fraction of the solved search space", progress.Fraction)
+	writeMetric(w, "c4solver_eta_seconds", "gauge", "Estimated time left, 0 if unknown", progress.ETA.Seconds())
+
+	writeMetric(w, "c4solver_cache_hits_total", "counter", "Number of endings taken from cache", progress.Cache.Hits)
+	writeMetric(w, "c4solver_cache_clears_total", "counter", "Number of cache depth clears", progress.Cache.Clears)
+	writeHeader(w, "c4solver_cache_entries", "gauge", "Number of cached endings by depth")
+	for depth, size := range progress.Cache.DepthSizes {
+		fmt.Fprintf(w, "c4solver_cache_entries{depth=\"%d\"} %d\n", depth, size)
+	}
+	writeHeader(w, "c4solver_cache_depth_clears_total", "counter", "Number of cache clears by depth")
+	for depth, clears := range progress.Cache.DepthClears {
+		fmt.Fprintf(w, "c4solver_cache_depth_clears_total{depth=\"%d\"} %d\n", depth, clears)
+	}
+
+	writeMetric(w, "c4solver_memory_heap_alloc_bytes", "gauge", "Bytes of allocated heap objects", memStats.HeapAlloc)
+	writeMetric(w, "c4solver_memory_sys_bytes", "gauge", "Bytes of memory obtained from the OS", memStats.Sys)
+	writeMetric(w, "c4solver_memory_gc_cycles_total", "counter", "Number of completed GC cycles", memStats.NumGC)
+	writeMetric(w, "c4solver_goroutines", "gauge", "Number of goroutines", runtime.NumGoroutine())
+}
+
+func writeHeader(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeMetric(w io.Writer, name string, kind string, help string, value interface{}) {
+	writeHeader(w, name, kind, help)
+	fmt.Fprintf(w, "%s %v\n", name, value)
+}
+
+// StartMetricsServer listens on the address and serves metrics at /metrics in the background
+func StartMetricsServer(addr string, collector *MetricsCollector) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "listening for metrics")
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", collector)
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Error("Metrics server failed", log.Ctx{"error": err})
+		}
+	}()
+	log.Info("Serving metrics", log.Ctx{"address": listener.Addr().String()})
+	return nil
+}
@@ -0,0 +1,49 @@
+package solver
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/igrek51/connect4solver/solver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsCollector(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.OnProgress(Progress{
+		Fraction:         0.5,
+		ETA:              2 * time.Minute,
+		Iterations:       3_000_000,
+		IterationsPerSec: 1_500_000,
+		Cache: CacheStats{
+			Size:        7,
+			Hits:        11,
+			Clears:      1,
+			DepthSizes:  []int{2, 5},
+			DepthClears: []uint64{0, 1},
+		},
+		Board: NewBoard(),
+	})
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL)
+	assert.NoError(t, err)
+	defer response.Body.Close()
+	assert.Contains(t, response.Header.Get("Content-Type"), "text/plain")
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	metrics := string(body)
+
+	assert.Contains(t, metrics, "# TYPE c4solver_iterations gauge\nc4solver_iterations 3000000\n")
+	assert.Contains(t, metrics, "\nc4solver_iterations_per_second 1500000\n")
+	assert.Contains(t, metrics, "\nc4solver_progress_ratio 0.5\n")
+	assert.Contains(t, metrics, "\nc4solver_eta_seconds 120\n")
+	assert.Contains(t, metrics, "\nc4solver_cache_hits_total 11\n")
+	assert.Contains(t, metrics, "\nc4solver_cache_entries{depth=\"1\"} 5\n")
+	assert.NotContains(t, metrics, "c4solver_cache_entries_total")
+	assert.Contains(t, metrics, "\nc4solver_cache_depth_clears_total{depth=\"1\"} 1\n")
+	assert.Contains(t, metrics, "\n# TYPE c4solver_memory_heap_alloc_bytes gauge\n")
+}
@@ -1,6 +1,7 @@
 package solver
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -12,30 +13,38 @@ import (
 )
 
 func Play(
-	width, height, winStreak int,
+	boardOptions []common.Option,
 	cacheEnabled, hideA, hideB,
 	autoAttackA, autoAttackB,
 	scoresEnabled bool,
 	startWithMoves string,
+	solverOptions ...common.SolverOption,
 ) {
 	rand.Seed(time.Now().UnixNano())
 
-	board := common.NewBoard(common.WithSize(width, height), common.WithWinStreak(winStreak))
+	board := common.NewBoard(boardOptions...)
 	board.ApplyMoves(startWithMoves)
 
-	solver := CreateSolver(board)
-	if cacheEnabled && common.CacheFileExists(board) {
-		common.MustLoadCache(solver.Cache(), board.W, board.H)
+	solver := CreateSolver(board, solverOptions...)
+	if cacheEnabled && CacheFileExists(board) {
+		MustLoadCache(solver.Cache(), board)
 	}
 
+	isAuto := func(player common.Player) bool {
+		return (player == common.PlayerA && autoAttackA) || (player == common.PlayerB && autoAttackB)
+	}
+	game := common.NewGame(board)
 	for {
 		startTime := time.Now()
-		endings := solver.MovesEndings(board)
-		if endings == nil {
-			endings = getCachedPlayerEndgames(board, solver)
+		player := board.NextPlayer()
+		autoAttack := isAuto(player)
+		ctx, stop := common.InterruptContext(context.Background())
+		endings, err := solvePlayEndings(ctx, solver, board, autoAttack)
+		stop()
+		if err != nil {
+			log.Warn("Solving failed, only cached endings are known", log.Ctx{"error": err})
 		}
 
-		player := board.NextPlayer()
 		scores := estimateMoveScores(solver, endings, player, board, scoresEnabled)
 		totalElapsed := time.Since(startTime)
 
@@ -48,90 +57,199 @@ func Play(
 		fmt.Println(board.String())
 		showHints := (player == common.PlayerA && !hideA) || (player == common.PlayerB && !hideB)
 		if showHints {
-			printEndingsLine(endings, player)
+			printEndingsLine(board, endings, player)
 			if scoresEnabled {
 				log.Info("Estimated move scores", log.Ctx{"scores": scores})
 			}
 		}
-		bestMove := findBestMove(scores)
+		bestMove := findBestMove(board, scores)
+		if bestMove < 0 {
+			log.Error("There is no move to play")
+			break
+		}
 
-		var move int
-		if (player == common.PlayerA && autoAttackA) || (player == common.PlayerB && autoAttackB) {
-			move = bestMove
+		command := moveCommand
+		move := bestMove
+		if autoAttack {
 			playerEnding := common.EndingForPlayer(endings[move], player)
-			fmt.Printf("Player %v moves: %d (%v)\n", player, move, playerEnding)
+			fmt.Printf("Player %s moves: %s (%s)\n", common.Display.Player(player), board.MoveString(move),
+				common.Display.Ending(playerEnding))
+		} else {
+			command, move = readNextMove(endings, game, bestMove, showHints)
+		}
+
+		if command == undoCommand {
+			if !undoTurn(game, isAuto) {
+				log.Error("Nothing to undo")
+			}
+			continue
+		} else if command == redoCommand {
+			if !redoTurn(game, isAuto) {
+				log.Error("Nothing to redo")
+				continue
+			}
 		} else {
-			move = readNextMove(endings, player, board, bestMove, showHints)
+			game.Play(move, endings[move])
 		}
 
-		moveY := board.Throw(move, player)
-		if solver.HasPlayerWon(board, move, moveY, player) {
+		last, _ := game.LastMove()
+		if winner := moveWinner(solver, board, last.Move, last.Y, last.Player); winner != common.Empty {
 			depth := board.CountMoves()
 			fmt.Println(board.String())
-			log.Info(fmt.Sprintf("Player %v won in %d moves", player, depth))
+			log.Info(fmt.Sprintf("Player %s won in %d moves", common.Display.Player(winner), depth))
 			break
-		} else if isATie(board) {
+		} else if isATie(board) || game.Repetitions() >= common.MaxRepetitions {
 			depth := board.CountMoves()
 			fmt.Println(board.String())
-			log.Info(fmt.Sprintf("%v in %d moves", common.Tie, depth))
+			log.Info(fmt.Sprintf("%s in %d moves", common.Display.Ending(common.Tie), depth))
 			break
 		}
 	}
 }
 
+type playCommand int
+
+const (
+	moveCommand playCommand = iota
+	undoCommand
+	redoCommand
+)
+
+// undoTurn takes back the last move, together with the computer's move before, so that the human moves again
+func undoTurn(game *common.Game, isAuto func(common.Player) bool) bool {
+	if _, ok := game.Undo(); !ok {
+		return false
+	}
+	if last, ok := game.LastMove(); ok && isAuto(game.Board.NextPlayer()) && !isAuto(last.Player) {
+		game.Undo()
+	}
+	return true
+}
+
+// redoTurn plays the undone move again, together with the computer's reply
+func redoTurn(game *common.Game, isAuto func(common.Player) bool) bool {
+	if _, ok := game.Redo(); !ok {
+		return false
+	}
+	if isAuto(game.Board.NextPlayer()) {
+		game.Redo()
+	}
+	return true
+}
+
+// printGameHistory shows moves played so far with the endings they lead to according to the solver
+func printGameHistory(game *common.Game) {
+	moves := game.Moves()
+	if len(moves) == 0 {
+		fmt.Println("No moves played yet")
+		return
+	}
+	for ply, move := range moves {
+		fmt.Println(gameMoveString(game.Board, ply, move))
+	}
+}
+
+// gameMoveString shows the move number, the player, the move and its ending for the player, eg. "  3. A p2 Win"
+func gameMoveString(board *common.Board, ply int, move common.GameMove) string {
+	return fmt.Sprintf("%3d. %s %-2s %s", ply+1, common.Display.Player(move.Player), board.MoveString(move.Move),
+		common.Display.Ending(common.EndingForPlayer(move.Ending, move.Player)))
+}
+
+// moveWinner returns the winner after making a move or Empty.
+// Popping out may complete lines of both players at once, then the one who popped wins.
+// In misère variant, completing a line loses.
+func moveWinner(solver common.IMoveSolver, board *common.Board, move int, y int, player common.Player) common.Player {
+	return common.NewRules(board.Variant).MoveWinner(solver, board, move, y, player)
+}
+
+// isATie checks if next player has no possible moves
 func isATie(board *common.Board) bool {
-	for x := 0; x < board.W; x++ {
-		if board.CanMakeMove(x) {
+	player := board.NextPlayer()
+	for move := 0; move < board.MoveSlots(); move++ {
+		if board.CanPlay(move, player) {
 			return false
 		}
 	}
 	return true
 }
 
-func printEndingsLine(endings []common.Player, player common.Player) {
+// printEndingsLine shows endings of dropping to each column,
+// in PopOut variant endings of popping out are shown in a separate line
+func printEndingsLine(board *common.Board, endings []common.Player, player common.Player) {
 	if endings == nil {
 		return
 	}
+	for start := 0; start < len(endings); start += board.W {
+		suffix := ""
+		if start > 0 {
+			suffix = " pop"
+		}
+		fmt.Println(endingsLine(endings[start:start+board.W], player) + suffix)
+	}
+}
 
+func endingsLine(endings []common.Player, player common.Player) string {
 	displays := []string{}
 	for _, ending := range endings {
 		var display string
 		if ending == common.NoMove {
-			display = common.PlayerDisplays[common.NoMove]
+			display = common.Display.Player(common.NoMove)
 		} else {
 			playerEnding := common.EndingForPlayer(ending, player)
-			display = common.ShortGameEndingDisplays[playerEnding]
+			display = common.Display.ShortEnding(playerEnding)
 		}
 		displays = append(displays, display)
 	}
-	fmt.Println("| " + strings.Join(displays, " ") + " |")
+	return "| " + strings.Join(displays, " ") + " |"
 }
 
+// readNextMove reads a move of the human player or a command: undo, redo and history, which is shown right away
 func readNextMove(
-	endings []common.Player, player common.Player, board *common.Board,
+	endings []common.Player, game *common.Game,
 	bestMove int, showBest bool,
-) int {
+) (playCommand, int) {
+	board := game.Board
+	player := board.NextPlayer()
 	for {
-		var move int
+		var input string
 		bestStr := ""
 		if showBest {
-			bestStr = fmt.Sprintf(" (Best: %d)", bestMove)
+			bestStr = fmt.Sprintf(" (Best: %s)", board.MoveString(bestMove))
+		}
+		popStr := ""
+		if board.Variant == common.PopOutVariant {
+			popStr = fmt.Sprintf(", p0-p%d", board.W-1)
 		}
-		fmt.Printf("Player %v moves [0-%d]%s: ", player, board.W-1, bestStr)
-		_, err := fmt.Scanf("%d", &move)
+		fmt.Printf("Player %s moves [0-%d%s, u - undo, r - redo, h - history]%s: ",
+			common.Display.Player(player), board.W-1, popStr, bestStr)
+		_, err := fmt.Scanf("%s", &input)
 		if err != nil {
-			log.Error("Invalid number", log.Ctx{"error": err})
+			log.Error("Invalid move", log.Ctx{"error": err})
 			continue
 		}
-		if move < 0 || move >= board.W {
-			log.Error("Move number is out of range")
+		switch input {
+		case "u", "undo":
+			return undoCommand, 0
+		case "r", "redo":
+			return redoCommand, 0
+		case "h", "history":
+			printGameHistory(game)
 			continue
 		}
-		if endings != nil && !board.CanMakeMove(move) {
-			log.Error("Column is already full")
+		move, err := board.ParseMove(input)
+		if err != nil {
+			log.Error("Invalid move", log.Ctx{"error": err})
 			continue
 		}
-		return move
+		if endings != nil && !board.CanPlay(move, player) {
+			if board.IsPopMove(move) {
+				log.Error("Can't pop out from the column")
+			} else {
+				log.Error("Column is already full")
+			}
+			continue
+		}
+		return moveCommand, move
 	}
 }
 
@@ -148,12 +266,12 @@ func estimateMoveScores(
 			continue
 		}
 
-		moveY := board.Throw(move, player)
+		moveY := board.MakeMove(move, player)
 		score := 0
 
-		if solver.HasPlayerWon(board, move, moveY, player) {
+		if winner := moveWinner(solver, board, move, moveY, player); winner == player {
 			score = 100
-		} else if solver.HasPlayerWon(board, move, moveY, opponent) {
+		} else if winner == opponent {
 			score = -100
 		} else {
 			if ending == player {
@@ -174,33 +292,86 @@ func estimateMoveScores(
 		}
 
 		scores[move] = score
-		board.Revert(move, moveY)
+		board.UndoMove(move, moveY, player)
+	}
+	if prover, ok := solver.(common.IProofOrder); ok && countWinningMoves(endings, player) > 1 {
+		preferEarliestWin(scores, endings, prover.MovesProofOrder(board), player)
 	}
 	return scores
 }
 
-func findBestMove(scores []int) int {
+func countWinningMoves(endings []common.Player, player common.Player) int {
+	count := 0
+	for _, ending := range endings {
+		if ending == player {
+			count++
+		}
+	}
+	return count
+}
+
+// preferEarliestWin lowers scores of winning moves other than the earliest proved one.
+// When positions may repeat, following any winning move could go round in circles until a tie by repetition.
+func preferEarliestWin(scores []int, endings []common.Player, orders []int, player common.Player) {
+	if orders == nil {
+		return
+	}
+	earliest := -1
+	for move, ending := range endings {
+		if ending == player && (earliest < 0 || orders[move] < orders[earliest]) {
+			earliest = move
+		}
+	}
+	for move, ending := range endings {
+		if ending == player && orders[move] > orders[earliest] {
+			scores[move] -= 20
+		}
+	}
+}
+
+// findBestMove chooses the playable move with the highest score, -1 if there is none.
+// Unknown endings are scored the same as illegal moves, so the board has to be checked.
+func findBestMove(board *common.Board, scores []int) int {
+	player := board.NextPlayer()
 	order := rand.Perm(len(scores)) // get random if there are many maximum values
-	maxi := order[0]
+	maxi := -1
 	for _, move := range order {
-		if scores[move] > scores[maxi] {
+		if board.CanPlay(move, player) && (maxi < 0 || scores[move] > scores[maxi]) {
 			maxi = move
 		}
 	}
 	return maxi
 }
 
+// solvePlayEndings finds endings of the moves to choose from.
+// When solving fails, it returns the cached endings (NoMove if unknown) along with the error.
+func solvePlayEndings(
+	ctx context.Context, solver common.IMoveSolver, board *common.Board, autoAttack bool,
+) ([]common.Player, error) {
+	if autoAttack {
+		// winning move found in cache is enough to play perfectly, other moves don't have to be solved
+		if endings := getCachedWinningEndings(board, solver); endings != nil {
+			return endings, nil
+		}
+	}
+	endings, err := solver.MovesEndingsContext(ctx, board)
+	if err != nil {
+		return getCachedPlayerEndgames(board, solver), err
+	}
+	return endings, nil
+}
+
 func getCachedPlayerEndgames(board *common.Board, solver common.IMoveSolver) []common.Player {
-	endings := make([]common.Player, board.W)
+	endings := make([]common.Player, board.MoveSlots())
 	player := board.NextPlayer()
 	depth := board.CountMoves()
-	for move := 0; move < board.W; move++ {
-		if !board.CanMakeMove(move) {
+	for move := 0; move < board.MoveSlots(); move++ {
+		if !board.CanPlay(move, player) {
 			endings[move] = common.NoMove
 			continue
 		}
 
-		moveY := board.Throw(move, player)
+		moveY := board.MakeMove(move, player)
 
 		ending, ok := solver.Cache().Get(board, depth)
 		if !ok {
@@ -209,7 +380,36 @@ func getCachedPlayerEndgames(board *common.Board, solver common.IMoveSolver) []c
 			endings[move] = ending
 		}
 
-		board.Revert(move, moveY)
+		board.UndoMove(move, moveY, player)
+	}
+	return endings
+}
+
+// getCachedWinningEndings returns known endings of moves if any of them is a win for the next player, otherwise nil
+func getCachedWinningEndings(board *common.Board, solver common.IMoveSolver) []common.Player {
+	player := board.NextPlayer()
+	endings := getKnownEndings(board, solver)
+	for _, ending := range endings {
+		if ending == player {
+			return endings
+		}
+	}
+	return nil
+}
+
+// getKnownEndings finds endings of moves completing a line or cached ones without solving, unknown endings are NoMove
+func getKnownEndings(board *common.Board, solver common.IMoveSolver) []common.Player {
+	player := board.NextPlayer()
+	endings := getCachedPlayerEndgames(board, solver)
+	for move := 0; move < board.MoveSlots(); move++ {
+		if !board.CanPlay(move, player) {
+			continue
+		}
+		moveY := board.MakeMove(move, player)
+		if winner := moveWinner(solver, board, move, moveY, player); winner != common.Empty {
+			endings[move] = winner
+		}
+		board.UndoMove(move, moveY, player)
 	}
 	return endings
 }
@@ -0,0 +1,141 @@
+package popout_solver
+
+import (
+	"github.com/igrek51/connect4solver/solver/common"
+	log "github.com/igrek51/log15"
+)
+
+// EndingCache keeps best endings of positions grouped by number of tokens on board.
+// Unlike in standard game, the player to move can't be deduced from the board, so it's a part of a key.
+type EndingCache struct {
+	depthCaches            []map[uint64]common.Player
+	maxCacheDepthSize      int
+	maxCachedDepth         uint
+	maxUnclearedCacheDepth uint
+
+	cachedEntries uint64
+	cacheUsages   uint64
+	clears        uint64
+	depthClears   []uint64
+	logger        log.Logger
+
+	boardW  int
+	boardH  int
+	boardW1 int
+	sideW   int
+}
+
+// NewEndingCache creates cache limited to given number of entries, writing log records to the logger
+func NewEndingCache(boardW int, boardH int, sizeLimit int, logger log.Logger) *EndingCache {
+	depths := boardW*boardH + 1
+	depthCaches := make([]map[uint64]common.Player, depths)
+	for i := 0; i < depths; i++ {
+		depthCaches[i] = make(map[uint64]common.Player)
+	}
+
+	return &EndingCache{
+		depthCaches:            depthCaches,
+		depthClears:            make([]uint64, depths),
+		maxCacheDepthSize:      sizeLimit / depths,
+		maxCachedDepth:         uint(boardW * boardH),
+		maxUnclearedCacheDepth: 16,
+		logger:                 logger,
+		boardW:                 boardW,
+		boardH:                 boardH,
+		boardW1:                boardW - 1,
+		sideW:                  boardW / 2,
+	}
+}
+
+// Get looks up the ending of a board, depth is derived from the number of tokens on board
+func (s *EndingCache) Get(board *common.Board, _ uint) (ending common.Player, ok bool) {
+	return s.get(board.CountMoves(), s.boardKey(board.State, board.NextPlayer()))
+}
+
+// Put stores the ending of a board, depth is derived from the number of tokens on board
+func (s *EndingCache) Put(board *common.Board, _ uint, ending common.Player) common.Player {
+	return s.put(board.CountMoves(), s.boardKey(board.State, board.NextPlayer()), ending)
+}
+
+func (s *EndingCache) get(tokens uint, key uint64) (ending common.Player, ok bool) {
+	ending, ok = s.depthCaches[tokens][key]
+	return
+}
+
+func (s *EndingCache) put(tokens uint, key uint64, ending common.Player) common.Player {
+	if s.DepthSize(tokens) >= s.maxCacheDepthSize && tokens > s.maxUnclearedCacheDepth {
+		s.ClearCache(tokens)
+	}
+	s.depthCaches[tokens][key] = ending
+	s.cachedEntries++
+	return ending
+}
+
+func (s *EndingCache) ClearCache(depth uint) {
+	s.logger.Debug("clearing cache", log.Ctx{"depth": depth})
+	s.cachedEntries -= uint64(s.DepthSize(depth))
+	s.depthCaches[depth] = make(map[uint64]common.Player)
+	s.depthClears[depth]++
+	s.clears++
+}
+
+func (s *EndingCache) Size() uint64 {
+	return s.cachedEntries
+}
+
+func (s *EndingCache) DepthSize(depth uint) int {
+	return len(s.depthCaches[depth])
+}
+
+// boardKey joins reflected board state with the player to move on the most significant bit
+func (s *EndingCache) boardKey(key common.BoardKey, nextPlayer common.Player) uint64 {
+	return s.reflectedBoardKey(key) | uint64(nextPlayer)<<63
+}
+
+func (s *EndingCache) reflectedBoardKey(key common.BoardKey) uint64 {
+	leftKey := key[0]
+	rightKey := key[s.boardW1]
+	for i := 1; i < s.sideW; i++ {
+		leftKey |= key[i] << (8 * i)
+		rightKey |= key[s.boardW1-i] << (8 * i)
+	}
+
+	if leftKey <= rightKey {
+		for i := s.sideW; i < s.boardW; i++ {
+			leftKey |= key[i] << (8 * i)
+		}
+		return leftKey
+	}
+	// mirror map
+	for i := s.sideW; i < s.boardW; i++ {
+		rightKey |= key[s.boardW1-i] << (8 * i)
+	}
+	return rightKey
+}
+
+func (s *EndingCache) MaxCachedDepth() uint {
+	return s.maxCachedDepth
+}
+
+func (s *EndingCache) DepthCaches() []map[uint64]common.Player {
+	return s.depthCaches
+}
+
+func (s *EndingCache) SetEntry(depth int, key uint64, value common.Player) {
+	s.depthCaches[depth][key] = value
+	s.cachedEntries++
+}
+
+func (s *EndingCache) Stats() common.CacheStats {
+	depthSizes := make([]int, len(s.depthCaches))
+	for depth, depthCache := range s.depthCaches {
+		depthSizes[depth] = len(depthCache)
+	}
+	return common.CacheStats{
+		Size:        s.cachedEntries,
+		Hits:        s.cacheUsages,
+		Clears:      s.clears,
+		DepthSizes:  depthSizes,
+		DepthClears: append([]uint64{}, s.depthClears...),
+	}
+}
@@ -0,0 +1,371 @@
+package popout_solver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
+
+	"github.com/igrek51/connect4solver/solver/common"
+	"github.com/igrek51/connect4solver/solver/generic_solver"
+)
+
+// MaxPositions limits the size of positions table, so only small boards can be solved
+const MaxPositions = 64_000_000
+
+// MoveSolver solves PopOut variant, where player may pop out own token from the bottom instead of dropping.
+// Since positions may repeat, the game tree is infinite, so the solver uses retrograde analysis:
+// all positions are evaluated repeatedly until no more wins can be proved.
+// Positions left undecided are ties, as none of the players can force a win (game repeats forever).
+type MoveSolver struct {
+	cache      *EndingCache
+	referee    *generic_solver.BitboardReferee
+	movesOrder []int
+	ctx        context.Context
+	// alternating turns are required, since the player to move is a part of the position
+	alternating bool
+	rectangular bool
+	W           int
+	H           int
+
+	// positions table indexed by column states and player to move
+	columnStates uint64
+	positions    uint64
+	endings      []common.Player
+	// provedSweeps keeps the sweep in which the ending of the position was proved
+	provedSweeps []uint16
+	solved       bool
+
+	startTime          time.Time
+	lastBoardPrintTime time.Time
+	progress           common.ProgressListener
+	logger             log.Logger
+	iterations         uint64
+	lastIterations     uint64
+	sweeps             int
+}
+
+// NewMoveSolver creates PopOut solver, search options don't apply to retrograde analysis, so only cache options are used
+func NewMoveSolver(board *common.Board, options ...common.SolverOption) *MoveSolver {
+	config := common.NewSolverConfig(options...)
+	dropsOrder := common.CalculateMovesOrder(board)
+	movesOrder := append([]int{}, dropsOrder...)
+	for _, x := range dropsOrder {
+		movesOrder = append(movesOrder, board.PopMove(x))
+	}
+	cache := configuredCache(board, config)
+
+	// column state has a leading one, so there are 2^(H+1)-1 states from 0b1 to 0b11...1
+	columnStates := uint64(1)<<(board.H+1) - 1
+	positions := uint64(2)
+	for x := 0; x < board.W && positions <= MaxPositions; x++ {
+		positions *= columnStates
+	}
+
+	config.Logger.Debug("Solver configured", log.Ctx{
+		"boardWidth":  board.W,
+		"boardHeight": board.H,
+		"winStreak":   board.WinStreak,
+		"variant":     board.Variant,
+		"movesOrder":  movesOrder,
+		"positions":   positions,
+	})
+	return &MoveSolver{
+		W:                  board.W,
+		H:                  board.H,
+		cache:              cache,
+		referee:            generic_solver.NewBitboardReferee(board),
+		movesOrder:         movesOrder,
+		alternating:        board.Turns.IsAlternating(),
+		rectangular:        board.IsRectangular(),
+		columnStates:       columnStates,
+		positions:          positions,
+		lastBoardPrintTime: time.Now(),
+		startTime:          time.Now(),
+		progress:           config.Progress,
+		logger:             config.Logger,
+		ctx:                context.Background(),
+	}
+}
+
+// configuredCache reuses the cache injected in config or creates a new one
+func configuredCache(board *common.Board, config *common.SolverConfig) *EndingCache {
+	if config.Cache == nil {
+		return NewEndingCache(board.W, board.H, config.CacheLimit, config.Logger)
+	}
+	cache, ok := config.Cache.(*EndingCache)
+	if !ok {
+		panic(errors.New("injected cache doesn't belong to PopOut solver"))
+	}
+	return cache
+}
+
+// MovesEndings solves endings of all moves, it stops on SIGINT returning nil
+func (s *MoveSolver) MovesEndings(board *common.Board) []common.Player {
+	ctx, stop := common.InterruptContext(context.Background())
+	defer stop()
+	endings, err := s.MovesEndingsContext(ctx, board)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("Solving failed", log.Ctx{"error": err})
+		}
+		return nil
+	}
+	return endings
+}
+
+// MovesEndingsContext solves endings of all moves, it stops when the context is done returning its error
+func (s *MoveSolver) MovesEndingsContext(ctx context.Context, board *common.Board) ([]common.Player, error) {
+	player := board.NextPlayer()
+	endings, ok := s.cachedMovesEndings(board, player)
+	if ok {
+		return endings, nil
+	}
+	if !s.solved {
+		if err := s.SolveContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, move := range s.movesOrder {
+		if !board.CanPlay(move, player) {
+			endings[move] = common.NoMove
+			continue
+		}
+		y := board.MakeMove(move, player)
+		ending := s.moveWinner(board, move, y, player)
+		if ending == common.Empty {
+			nextPlayer := common.OppositePlayer(player)
+			ending = s.endings[s.positionIndex(board, nextPlayer)]
+			s.cache.put(board.CountMoves(), s.cache.boardKey(board.State, nextPlayer), ending)
+		}
+		endings[move] = ending
+		board.UndoMove(move, y, player)
+	}
+	return endings, nil
+}
+
+// cachedMovesEndings gets endings from cache, not solving anything
+func (s *MoveSolver) cachedMovesEndings(board *common.Board, player common.Player) ([]common.Player, bool) {
+	endings := make([]common.Player, board.MoveSlots())
+	found := true
+	for _, move := range s.movesOrder {
+		if !board.CanPlay(move, player) {
+			endings[move] = common.NoMove
+			continue
+		}
+		y := board.MakeMove(move, player)
+		ending := s.moveWinner(board, move, y, player)
+		if ending == common.Empty {
+			var ok bool
+			ending, ok = s.cache.get(board.CountMoves(), s.cache.boardKey(board.State, common.OppositePlayer(player)))
+			found = found && ok
+		}
+		endings[move] = ending
+		board.UndoMove(move, y, player)
+	}
+	return endings, found
+}
+
+// Solve evaluates all positions of the board, it stops on SIGINT
+func (s *MoveSolver) Solve() error {
+	ctx, stop := common.InterruptContext(context.Background())
+	defer stop()
+	return s.SolveContext(ctx)
+}
+
+// SolveContext evaluates all positions of the board, it stops when the context is done returning its error
+func (s *MoveSolver) SolveContext(ctx context.Context) (err error) {
+	if s.positions > MaxPositions {
+		return fmt.Errorf("board is too big for PopOut solver, it has over %d positions", MaxPositions)
+	}
+	if !s.alternating {
+		return fmt.Errorf("PopOut solver supports only alternating turns")
+	}
+	if !s.rectangular {
+		return fmt.Errorf("PopOut solver supports only rectangular boards without neutral tokens")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = common.InterruptCause(ctx, r, s.logger)
+		}
+	}()
+	s.ctx = ctx
+	defer func() { s.ctx = context.Background() }()
+
+	s.startTime = time.Now()
+	s.lastBoardPrintTime = time.Now()
+	s.iterations = 0
+	s.sweeps = 0
+
+	// positions with lines are never played from, the game is over before
+	board := common.NewBoard(common.WithSize(s.W, s.H), common.WithVariant(common.PopOutVariant))
+	s.endings = make([]common.Player, s.positions)
+	s.provedSweeps = make([]uint16, s.positions)
+	undecided := []uint64{}
+	for index := uint64(0); index < s.positions; index++ {
+		s.endings[index] = common.NoMove
+		s.decodePosition(index, board)
+		if !s.referee.HasPlayerLine(board, common.PlayerA) && !s.referee.HasPlayerLine(board, common.PlayerB) {
+			undecided = append(undecided, index)
+		}
+	}
+	allUndecided := len(undecided)
+
+	for changed := true; changed; {
+		changed = false
+		s.sweeps++
+		remaining := undecided[:0]
+		for _, index := range undecided {
+			s.iterations++
+			player := s.decodePosition(index, board)
+			ending := s.evaluatePosition(board, player)
+			if ending == common.NoMove {
+				remaining = append(remaining, index)
+			} else {
+				s.endings[index] = ending
+				s.provedSweeps[index] = uint16(s.sweeps)
+				changed = true
+			}
+			s.reportCycle(board, 1-float64(len(undecided))/float64(allUndecided))
+		}
+		undecided = remaining
+	}
+	// nobody can force a win, players may repeat moves forever
+	for _, index := range undecided {
+		s.endings[index] = common.Empty
+	}
+
+	s.solved = true
+	s.logger.Debug("PopOut positions solved", log.Ctx{
+		"positions": allUndecided,
+		"ties":      len(undecided),
+		"sweeps":    s.sweeps,
+		"duration":  time.Since(s.startTime),
+	})
+	return nil
+}
+
+// MovesProofOrder tells the order in which endings after each move were proved (0 - game ends with the move).
+// Cached endings don't keep the order, so the positions are solved if they haven't been yet, nil if solving fails.
+// Sweeps visit positions in order of their indices and each ending is proved upon the earlier ones,
+// so the winner taking the earliest proved win always gets closer to the end, rather than repeating positions.
+func (s *MoveSolver) MovesProofOrder(board *common.Board) []int {
+	if !s.solved {
+		if err := s.Solve(); err != nil {
+			s.logger.Error("Solving failed", log.Ctx{"error": err})
+			return nil
+		}
+	}
+	player := board.NextPlayer()
+	orders := make([]int, board.MoveSlots())
+	for move := range orders {
+		if !board.CanPlay(move, player) {
+			continue
+		}
+		y := board.MakeMove(move, player)
+		if s.moveWinner(board, move, y, player) == common.Empty {
+			index := s.positionIndex(board, common.OppositePlayer(player))
+			orders[move] = int(s.provedSweeps[index])*int(s.positions) + int(index)
+		}
+		board.UndoMove(move, y, player)
+	}
+	return orders
+}
+
+// evaluatePosition returns proved ending or NoMove if it's still unknown
+func (s *MoveSolver) evaluatePosition(board *common.Board, player common.Player) common.Player {
+	opponent := common.OppositePlayer(player)
+	possibleMoves := 0
+	lostMoves := 0
+	for _, move := range s.movesOrder {
+		if !board.CanPlay(move, player) {
+			continue
+		}
+		possibleMoves++
+		y := board.MakeMove(move, player)
+		ending := s.moveWinner(board, move, y, player)
+		if ending == common.Empty {
+			ending = s.endings[s.positionIndex(board, opponent)]
+		}
+		board.UndoMove(move, y, player)
+
+		if ending == player { // cant be better than winning
+			return player
+		}
+		if ending == opponent {
+			lostMoves++
+		}
+	}
+	if possibleMoves == 0 { // nothing to do
+		return common.Empty
+	}
+	if lostMoves == possibleMoves {
+		return opponent
+	}
+	return common.NoMove
+}
+
+func (s *MoveSolver) positionIndex(board *common.Board, nextPlayer common.Player) uint64 {
+	index := uint64(0)
+	for x := s.W - 1; x >= 0; x-- {
+		index = index*s.columnStates + board.State[x] - 1
+	}
+	return index*2 + uint64(nextPlayer)
+}
+
+// decodePosition sets board state from position index and returns player to move
+func (s *MoveSolver) decodePosition(index uint64, board *common.Board) common.Player {
+	nextPlayer := common.Player(index % 2)
+	index /= 2
+	for x := 0; x < s.W; x++ {
+		board.State[x] = index%s.columnStates + 1
+		index /= s.columnStates
+	}
+	return nextPlayer
+}
+
+// moveWinner returns the winner after making a move or Empty.
+// Popping out may complete lines of both players at once, then the one who popped wins.
+func (s *MoveSolver) moveWinner(board *common.Board, move int, y int, player common.Player) common.Player {
+	if !board.IsPopMove(move) {
+		if s.referee.HasPlayerWon(board, move, y, player) {
+			return player
+		}
+		return common.Empty
+	}
+	if s.referee.HasPlayerLine(board, player) {
+		return player
+	}
+	opponent := common.OppositePlayer(player)
+	if s.referee.HasPlayerLine(board, opponent) {
+		return opponent
+	}
+	return common.Empty
+}
+
+// HasPlayerWon checks if player has a winning line after the move.
+// After popping out both players might have lines, so the popping player should be checked first.
+func (s *MoveSolver) HasPlayerWon(board *common.Board, move int, y int, player common.Player) bool {
+	if board.IsPopMove(move) {
+		return s.referee.HasPlayerLine(board, player)
+	}
+	return s.referee.HasPlayerWon(board, move, y, player)
+}
+
+func (s *MoveSolver) Retrain(board *common.Board, maxDepth uint) {
+	s.logger.Warn("Retraining is not supported in PopOut variant")
+}
+
+func (s *MoveSolver) RetrainContext(ctx context.Context, board *common.Board, maxDepth uint) error {
+	return errors.New("retraining is not supported in PopOut variant")
+}
+
+func (s *MoveSolver) Cache() common.ICache {
+	return s.cache
+}
@@ -0,0 +1,147 @@
+package popout_solver
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/igrek51/connect4solver/solver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolveSmallBoards(t *testing.T) {
+	testCases := []struct {
+		w, h, winStreak int
+		winner          Player
+	}{
+		{2, 2, 2, PlayerA},
+		{3, 3, 3, PlayerB},
+		{4, 3, 3, PlayerA},
+		{2, 3, 3, Empty},
+		{3, 3, 4, Empty},
+	}
+	for _, tc := range testCases {
+		board := NewBoard(WithSize(tc.w, tc.h), WithWinStreak(tc.winStreak), WithVariant(PopOutVariant))
+		solver := NewMoveSolver(board)
+		endings := solver.MovesEndings(board)
+
+		assert.Len(t, endings, 2*tc.w)
+		best := PlayerB
+		for move, ending := range endings {
+			if board.IsPopMove(move) {
+				assert.Equal(t, NoMove, ending)
+			} else if ending == PlayerA || (ending == Empty && best == PlayerB) {
+				best = ending
+			}
+		}
+		assert.Equal(t, tc.winner, best, "board %dx%d win %d", tc.w, tc.h, tc.winStreak)
+	}
+}
+
+func TestPopCompletingBothLines(t *testing.T) {
+	board := ParseBoard(`
+	B..
+	ABB
+	BAA
+	`, WithWinStreak(3), WithVariant(PopOutVariant))
+	board.Pops[PlayerA] = 1 // A has popped out before, so it's B's turn
+	solver := NewMoveSolver(board)
+	assert.Equal(t, PlayerB, board.NextPlayer())
+
+	endings := solver.MovesEndings(board)
+	assert.Equal(t, PlayerB, endings[board.PopMove(0)])
+
+	move := board.PopMove(0)
+	y := board.MakeMove(move, PlayerB)
+	assert.True(t, solver.HasPlayerWon(board, move, y, PlayerB))
+	assert.True(t, solver.HasPlayerWon(board, move, y, PlayerA))
+	assert.Equal(t, PlayerB, solver.moveWinner(board, move, y, PlayerB))
+}
+
+func TestCachedEndings(t *testing.T) {
+	board := NewBoard(WithSize(3, 3), WithWinStreak(3), WithVariant(PopOutVariant))
+	solver := NewMoveSolver(board)
+	endings := solver.MovesEndings(board)
+
+	solver2 := NewMoveSolver(board)
+	for depth, depthCache := range solver.Cache().DepthCaches() {
+		for key, value := range depthCache {
+			solver2.Cache().SetEntry(depth, key, value)
+		}
+	}
+	assert.Equal(t, endings, solver2.MovesEndings(board))
+	assert.False(t, solver2.solved)
+}
+
+func TestEarliestProvedWinMakesProgress(t *testing.T) {
+	board := NewBoard(WithSize(3, 3), WithWinStreak(3), WithVariant(PopOutVariant))
+	trained := NewMoveSolver(board)
+	trained.MovesEndings(board)
+
+	// endings loaded from the cache don't keep the proof order, so it's solved again when needed
+	solver := NewMoveSolver(board)
+	for depth, depthCache := range trained.Cache().DepthCaches() {
+		for key, value := range depthCache {
+			solver.Cache().SetEntry(depth, key, value)
+		}
+	}
+	solver.MovesEndings(board)
+	assert.False(t, solver.solved)
+	assert.Equal(t, trained.MovesProofOrder(board), solver.MovesProofOrder(board))
+	assert.True(t, solver.solved)
+
+	// B wins on 3x3 board, A tries every move in turn
+	visited := map[BoardKey]bool{}
+	for ply := 0; ; ply++ {
+		player := board.NextPlayer()
+		endings := solver.MovesEndings(board)
+		move := -1
+		if player == PlayerB {
+			orders := solver.MovesProofOrder(board)
+			for m, ending := range endings {
+				if ending == PlayerB && (move < 0 || orders[m] < orders[move]) {
+					move = m
+				}
+			}
+		} else {
+			for m := 0; m < board.MoveSlots(); m++ {
+				if board.CanPlay((m+ply)%board.MoveSlots(), player) {
+					move = (m + ply) % board.MoveSlots()
+					break
+				}
+			}
+		}
+		if !assert.NotEqual(t, -1, move) {
+			break
+		}
+		y := board.MakeMove(move, player)
+		if winner := solver.moveWinner(board, move, y, player); winner != Empty {
+			assert.Equal(t, PlayerB, winner)
+			break
+		}
+		if !assert.False(t, visited[board.State], "position repeated after %d moves", ply+1) {
+			break
+		}
+		visited[board.State] = true
+	}
+}
+
+func TestTooBigBoard(t *testing.T) {
+	board := NewBoard(WithSize(7, 6), WithVariant(PopOutVariant))
+	solver := NewMoveSolver(board)
+	assert.Nil(t, solver.MovesEndings(board))
+}
+
+func TestSolveContextCancelled(t *testing.T) {
+	board := NewBoard(WithSize(3, 3), WithWinStreak(3), WithVariant(PopOutVariant))
+	solver := NewMoveSolver(board)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	endings, err := solver.MovesEndingsContext(ctx, board)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, endings)
+
+	endings, err = solver.MovesEndingsContext(context.Background(), board)
+	assert.NoError(t, err)
+	assert.Len(t, endings, 6)
+}
@@ -0,0 +1,61 @@
+package popout_solver
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/igrek51/log15"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+func (s *MoveSolver) reportCycle(board *common.Board, progressStart float64) {
+	if s.iterations&common.ItReportPeriodMask == 0 {
+		if s.ctx.Err() != nil {
+			panic(common.InterruptError)
+		}
+		if time.Since(s.lastBoardPrintTime) >= common.RefreshProgressPeriod {
+			s.ReportStatus(board, progressStart)
+		}
+	}
+}
+
+func (s *MoveSolver) ReportStatus(
+	board *common.Board,
+	progress float64,
+) {
+	duration := time.Since(s.startTime)
+	instDuration := time.Since(s.lastBoardPrintTime)
+	iterationsPerSec := s.iterations / uint64(duration/time.Second)
+	instIterationsPerSec := (s.iterations - s.lastIterations) / uint64(instDuration/time.Second)
+	s.lastIterations = s.iterations
+	s.lastBoardPrintTime = time.Now()
+
+	s.logger.Debug("Currently considered board", log.Ctx{
+		"cacheSize":   common.BigintSeparated(s.cache.Size()),
+		"iterations":  common.BigintSeparated(s.iterations),
+		"cacheClears": common.BigintSeparated(s.cache.clears),
+		"sweeps":      s.sweeps,
+		"progress":    fmt.Sprintf("%v", progress),
+		"itsAvg":      common.BigintSeparated(iterationsPerSec),
+		"its":         common.BigintSeparated(instIterationsPerSec),
+	})
+	if s.progress != nil {
+		s.progress.OnProgress(common.Progress{
+			Fraction:         progress,
+			Iterations:       s.iterations,
+			IterationsPerSec: instIterationsPerSec,
+			Cache:            s.cache.Stats(),
+			Board:            board.Clone(),
+		})
+	}
+}
+
+func (s *MoveSolver) SummaryVars() log.Ctx {
+	return log.Ctx{
+		"cacheSize":   common.BigintSeparated(s.cache.Size()),
+		"iterations":  common.BigintSeparated(s.iterations),
+		"cacheClears": common.BigintSeparated(s.cache.clears),
+		"sweeps":      s.sweeps,
+	}
+}
@@ -0,0 +1,100 @@
+package solver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// ProgressMode tells how CLI reports progress of solving
+type ProgressMode string
+
+const (
+	// BarProgress prints the considered board to stdout and moves the progress bar on stderr
+	BarProgress ProgressMode = "bar"
+	// JsonProgress writes progress events as JSON lines to stderr
+	JsonProgress ProgressMode = "json"
+	// NoProgress doesn't report progress
+	NoProgress ProgressMode = "none"
+)
+
+// NewProgressListener creates listener reporting progress in the chosen mode, nil for NoProgress
+func NewProgressListener(mode ProgressMode) (common.ProgressListener, error) {
+	switch mode {
+	case BarProgress:
+		return &barProgressListener{}, nil
+	case JsonProgress:
+		return newJsonProgressListener(os.Stderr), nil
+	case NoProgress:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown progress mode: %s", mode)
+}
+
+const ProgressBarResolution = 1_000_000_000
+
+func NewProgressBar() *progressbar.ProgressBar {
+	bar := progressbar.NewOptions64(
+		ProgressBarResolution,
+		progressbar.OptionSetDescription(""),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionSetWidth(10),
+		progressbar.OptionThrottle(65*time.Millisecond),
+		progressbar.OptionOnCompletion(func() {
+			fmt.Fprint(os.Stderr, "\n")
+		}),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetPredictTime(true),
+		progressbar.OptionShowCount(),
+	)
+	bar.RenderBlank()
+	return bar
+}
+
+type barProgressListener struct {
+	bar *progressbar.ProgressBar
+}
+
+func (l *barProgressListener) OnProgress(progress common.Progress) {
+	// bar is created on the first report, so that quick solves don't render it at all
+	if l.bar == nil {
+		l.bar = NewProgressBar()
+	}
+	fmt.Println(progress.Board.String())
+	l.bar.Set(int(progress.Fraction * ProgressBarResolution))
+}
+
+type progressEvent struct {
+	Progress         float64 `json:"progress"`
+	EtaSeconds       float64 `json:"etaSeconds"`
+	Iterations       uint64  `json:"iterations"`
+	IterationsPerSec uint64  `json:"iterationsPerSec"`
+	CacheSize        uint64  `json:"cacheSize"`
+	Board            string  `json:"board"`
+}
+
+type jsonProgressListener struct {
+	encoder *json.Encoder
+}
+
+func newJsonProgressListener(writer io.Writer) *jsonProgressListener {
+	return &jsonProgressListener{encoder: json.NewEncoder(writer)}
+}
+
+func (l *jsonProgressListener) OnProgress(progress common.Progress) {
+	l.encoder.Encode(progressEvent{
+		Progress:         progress.Fraction,
+		EtaSeconds:       progress.ETA.Round(time.Second).Seconds(),
+		Iterations:       progress.Iterations,
+		IterationsPerSec: progress.IterationsPerSec,
+		CacheSize:        progress.Cache.Size,
+		Board:            progress.Board.LayoutString(),
+	})
+}
@@ -0,0 +1,38 @@
+package solver
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/igrek51/connect4solver/solver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonProgressListener(t *testing.T) {
+	out := &bytes.Buffer{}
+	listener := newJsonProgressListener(out)
+	board := NewBoard(WithSize(3, 2)).ApplyMoves("01")
+
+	listener.OnProgress(Progress{
+		Fraction:         0.25,
+		ETA:              90 * time.Second,
+		Iterations:       2_000_000,
+		IterationsPerSec: 1_000_000,
+		Cache:            CacheStats{Size: 42},
+		Board:            board,
+	})
+	assert.Equal(t, `{"progress":0.25,"etaSeconds":90,"iterations":2000000,"iterationsPerSec":1000000,`+
+		`"cacheSize":42,"board":".../AB."}`+"\n", out.String())
+}
+
+func TestNewProgressListener(t *testing.T) {
+	listener, err := NewProgressListener(NoProgress)
+	assert.NoError(t, err)
+	assert.Nil(t, listener)
+	listener, err = NewProgressListener(JsonProgress)
+	assert.NoError(t, err)
+	assert.NotNil(t, listener)
+	_, err = NewProgressListener("verbose")
+	assert.Error(t, err)
+}
@@ -1,18 +1,16 @@
 package solver
 
 import (
+	"github.com/pkg/errors"
+
+	"github.com/igrek51/connect4solver/solver/backends"
 	"github.com/igrek51/connect4solver/solver/common"
-	"github.com/igrek51/connect4solver/solver/generic_solver"
-	"github.com/igrek51/connect4solver/solver/inline7x6"
 )
 
-func CreateSolver(board *common.Board) common.IMoveSolver {
-	var solver common.IMoveSolver
-	// take precedence with inlined optimized solvers
-	if board.W == 7 && board.H == 6 {
-		solver = inline7x6.NewMoveSolver(board)
-	} else {
-		solver = generic_solver.NewMoveSolver(board)
+func CreateSolver(board *common.Board, options ...common.SolverOption) common.IMoveSolver {
+	solver, err := backends.NewMoveSolver(board, backends.Auto, options...)
+	if err != nil {
+		panic(errors.Wrap(err, "creating solver"))
 	}
 	return solver
 }
@@ -3,6 +3,7 @@ package solver
 import (
 	"testing"
 
+	"github.com/igrek51/connect4solver/solver/backends"
 	. "github.com/igrek51/connect4solver/solver/common"
 	"github.com/stretchr/testify/assert"
 )
@@ -20,3 +21,34 @@ func Test7x6Solver(t *testing.T) {
 	endings := solver.MovesEndings(board)
 	assert.Equal(t, []Player{PlayerA, PlayerB, PlayerA, PlayerB, PlayerA, PlayerB, PlayerA}, endings)
 }
+
+func Test7x6SolverMisere(t *testing.T) {
+	board := ParseBoard(`
+	.......
+	.......
+	AABBAAB
+	BBAABBA
+	AABBAAB
+	BBAABBA
+	`, WithVariant(MisereVariant))
+	endings := CreateSolver(board).MovesEndings(board)
+	genericSolver, err := backends.NewMoveSolver(board, backends.Generic)
+	assert.NoError(t, err)
+	assert.Equal(t, []Player{PlayerB, PlayerB, PlayerB, PlayerB, PlayerB, PlayerB, PlayerB}, endings)
+	assert.Equal(t, endings, genericSolver.MovesEndings(board))
+}
+
+func Test7x6SolverTurnSchedule(t *testing.T) {
+	board := ParseBoard(`
+	.......
+	.......
+	AABBAAB
+	BBAABBA
+	AABBAAB
+	BBAABBA
+	`, WithTurnSchedule(TurnSchedule{1, 2}))
+	endings := CreateSolver(board).MovesEndings(board)
+	genericSolver, err := backends.NewMoveSolver(board, backends.Generic)
+	assert.NoError(t, err)
+	assert.Equal(t, endings, genericSolver.MovesEndings(board))
+}
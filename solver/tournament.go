@@ -0,0 +1,294 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
+
+	"github.com/igrek51/connect4solver/solver/backends"
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// EngineConfig describes a computer player taking part in a tournament
+type EngineConfig struct {
+	Name    string
+	Level   int // percentage of moves chosen by solver, the rest are random moves
+	Backend string
+	Cache   bool
+	Scores  bool
+	Order   common.MoveOrderPolicy
+	Forced  bool
+	Referee common.RefereeKind
+}
+
+// ParseEngineConfig reads engine configuration from comma separated list of key=value pairs,
+// eg. "level=80,backend=generic,cache=false,scores=true,order=threat"
+func ParseEngineConfig(spec string, defaultName string) (*EngineConfig, error) {
+	config := &EngineConfig{
+		Name:    defaultName,
+		Level:   100,
+		Backend: backends.Auto,
+		Cache:   true,
+		Order:   common.StaticMoveOrder,
+		Forced:  true,
+		Referee: common.LookupRefereeKind,
+	}
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return config, nil
+	}
+	for _, part := range strings.Split(spec, ",") {
+		keyValue := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(keyValue) != 2 {
+			return nil, fmt.Errorf("expected key=value pair, got: %s", part)
+		}
+		key, value := keyValue[0], keyValue[1]
+		var err error
+		switch key {
+		case "name":
+			config.Name = value
+		case "level":
+			config.Level, err = strconv.Atoi(value)
+			if err == nil && (config.Level < 0 || config.Level > 100) {
+				err = fmt.Errorf("level should be in range [0-100]")
+			}
+		case "backend":
+			config.Backend = value
+		case "cache":
+			config.Cache, err = strconv.ParseBool(value)
+		case "scores":
+			config.Scores, err = strconv.ParseBool(value)
+		case "forced":
+			config.Forced, err = strconv.ParseBool(value)
+		case "referee":
+			config.Referee, err = common.ParseRefereeKind(value)
+		case "order":
+			config.Order, err = common.ParseMoveOrderPolicy(value)
+		default:
+			err = fmt.Errorf("unknown engine option")
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing engine option %s", key)
+		}
+	}
+	return config, nil
+}
+
+// TournamentResult keeps match statistics from the perspective of the first engine
+type TournamentResult struct {
+	Games      int
+	Wins       int
+	Ties       int
+	Losses     int
+	WinsAsA    int
+	WinsAsB    int
+	TotalMoves int
+}
+
+// Score is an average points per game, counting 1 for a win and 0.5 for a tie
+func (r *TournamentResult) Score() float64 {
+	if r.Games == 0 {
+		return 0.5
+	}
+	return (float64(r.Wins) + 0.5*float64(r.Ties)) / float64(r.Games)
+}
+
+// EloDifference estimates rating difference between first and second engine
+func (r *TournamentResult) EloDifference() float64 {
+	score := r.Score()
+	if score <= 0 {
+		return math.Inf(-1)
+	}
+	if score >= 1 {
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/score-1)
+}
+
+func (r *TournamentResult) AverageGameLength() float64 {
+	if r.Games == 0 {
+		return 0
+	}
+	return float64(r.TotalMoves) / float64(r.Games)
+}
+
+type engine struct {
+	config *EngineConfig
+	solver common.IMoveSolver
+}
+
+// newEngine creates solver with given options, overridden by the engine's search options
+func newEngine(config *EngineConfig, board *common.Board, solverOptions ...common.SolverOption) (*engine, error) {
+	options := append(append([]common.SolverOption{}, solverOptions...),
+		common.WithMoveOrder(config.Order),
+		common.WithForcedMoves(config.Forced),
+		common.WithReferee(config.Referee),
+	)
+	solver, err := backends.NewMoveSolver(board, config.Backend, options...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating solver for engine %s", config.Name)
+	}
+	if config.Cache && CacheFileExists(board) {
+		if err := LoadCache(solver.Cache(), board); err != nil {
+			return nil, errors.Wrapf(err, "loading cache for engine %s", config.Name)
+		}
+	}
+	return &engine{
+		config: config,
+		solver: solver,
+	}, nil
+}
+
+func (e *engine) chooseMove(board *common.Board) int {
+	if rand.Intn(100) >= e.config.Level {
+		return randomMove(board)
+	}
+	endings := e.solver.MovesEndings(board)
+	if endings == nil {
+		return randomMove(board)
+	}
+	player := board.NextPlayer()
+	scores := estimateMoveScores(e.solver, endings, player, board, e.config.Scores)
+	return findBestMove(board, scores)
+}
+
+func randomMove(board *common.Board) int {
+	moves := []int{}
+	player := board.NextPlayer()
+	for move := 0; move < board.MoveSlots(); move++ {
+		if board.CanPlay(move, player) {
+			moves = append(moves, move)
+		}
+	}
+	return moves[rand.Intn(len(moves))]
+}
+
+func Tournament(
+	boardOptions []common.Option,
+	games, openings int,
+	seed int64,
+	engineSpecA, engineSpecB string,
+	solverOptions ...common.SolverOption,
+) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rand.Seed(seed)
+
+	board := common.NewBoard(boardOptions...)
+
+	configA, err := ParseEngineConfig(engineSpecA, "engine-a")
+	if err != nil {
+		log.Error("Invalid engine configuration", log.Ctx{"error": err})
+		return
+	}
+	configB, err := ParseEngineConfig(engineSpecB, "engine-b")
+	if err != nil {
+		log.Error("Invalid engine configuration", log.Ctx{"error": err})
+		return
+	}
+	first, err := newEngine(configA, board, solverOptions...)
+	if err != nil {
+		log.Error("Engine setup failed", log.Ctx{"error": err})
+		return
+	}
+	second, err := newEngine(configB, board, solverOptions...)
+	if err != nil {
+		log.Error("Engine setup failed", log.Ctx{"error": err})
+		return
+	}
+
+	log.Info("Starting tournament", log.Ctx{
+		"games":    games,
+		"openings": openings,
+		"seed":     seed,
+		"engine1":  fmt.Sprintf("%+v", *configA),
+		"engine2":  fmt.Sprintf("%+v", *configB),
+	})
+	startTime := time.Now()
+	result := RunTournament(board, first, second, games, openings)
+
+	log.Info("Tournament finished", log.Ctx{
+		"duration":      time.Since(startTime),
+		"games":         result.Games,
+		"wins":          result.Wins,
+		"ties":          result.Ties,
+		"losses":        result.Losses,
+		"winsAsA":       result.WinsAsA,
+		"winsAsB":       result.WinsAsB,
+		"avgGameLength": fmt.Sprintf("%.2f", result.AverageGameLength()),
+		"score":         fmt.Sprintf("%.3f", result.Score()),
+		"eloDifference": fmt.Sprintf("%+.0f", result.EloDifference()),
+	})
+}
+
+// RunTournament plays given number of games between two engines, alternating who starts
+func RunTournament(board *common.Board, first, second *engine, games, openings int) *TournamentResult {
+	result := &TournamentResult{}
+	for game := 0; game < games; game++ {
+		board.Clear()
+		engines := [2]*engine{first, second}
+		firstPlaysA := game%2 == 0
+		if !firstPlaysA {
+			engines = [2]*engine{second, first}
+		}
+
+		winner, moves := playTournamentGame(board, engines, openings)
+
+		result.Games++
+		result.TotalMoves += moves
+		firstPlayer := common.PlayerA
+		if !firstPlaysA {
+			firstPlayer = common.PlayerB
+		}
+		if winner == common.Empty {
+			result.Ties++
+		} else if winner == firstPlayer {
+			result.Wins++
+			if firstPlayer == common.PlayerA {
+				result.WinsAsA++
+			} else {
+				result.WinsAsB++
+			}
+		} else {
+			result.Losses++
+		}
+
+		log.Debug("Game finished", log.Ctx{
+			"game":    game + 1,
+			"playerA": engines[common.PlayerA].config.Name,
+			"playerB": engines[common.PlayerB].config.Name,
+			"winner":  winner,
+			"moves":   moves,
+		})
+	}
+	return result
+}
+
+// playTournamentGame returns winner (Empty on tie) and number of moves made
+func playTournamentGame(board *common.Board, engines [2]*engine, openings int) (common.Player, int) {
+	game := common.NewGame(board)
+	for moves := 1; ; moves++ {
+		player := board.NextPlayer()
+		current := engines[player]
+		var move int
+		if moves <= openings {
+			move = randomMove(board)
+		} else {
+			move = current.chooseMove(board)
+		}
+
+		gameMove := game.Play(move, common.NoMove)
+		if winner := moveWinner(current.solver, board, move, gameMove.Y, player); winner != common.Empty {
+			return winner, moves
+		} else if isATie(board) || game.Repetitions() >= common.MaxRepetitions {
+			return common.Empty, moves
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package solver
+
+import (
+	"math"
+	"testing"
+
+	"github.com/igrek51/connect4solver/solver/backends"
+	. "github.com/igrek51/connect4solver/solver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEngineConfig(t *testing.T) {
+	config, err := ParseEngineConfig("level=80, backend=generic,cache=false,scores=true,name=weak,order=threat,forced=false,referee=bitboard", "default")
+	assert.NoError(t, err)
+	assert.Equal(t, &EngineConfig{
+		Name:    "weak",
+		Level:   80,
+		Backend: backends.Generic,
+		Cache:   false,
+		Scores:  true,
+		Order:   ThreatMoveOrder,
+		Forced:  false,
+		Referee: BitboardRefereeKind,
+	}, config)
+
+	config, err = ParseEngineConfig("", "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "default", config.Name)
+	assert.Equal(t, 100, config.Level)
+
+	_, err = ParseEngineConfig("level=101", "default")
+	assert.Error(t, err)
+	_, err = ParseEngineConfig("depth=5", "default")
+	assert.Error(t, err)
+}
+
+func TestTournamentElo(t *testing.T) {
+	result := &TournamentResult{Games: 4, Wins: 1, Ties: 2, Losses: 1}
+	assert.InDelta(t, 0, result.EloDifference(), 0.001)
+
+	result = &TournamentResult{Games: 4, Wins: 3, Losses: 1}
+	assert.InDelta(t, 190.85, result.EloDifference(), 0.01)
+
+	result = &TournamentResult{Games: 2, Wins: 2}
+	assert.True(t, math.IsInf(result.EloDifference(), 1))
+}
+
+func TestTournamentPerfectEngines(t *testing.T) {
+	board := NewBoard(WithSize(3, 3), WithWinStreak(3))
+	config := &EngineConfig{Name: "perfect", Level: 100, Backend: backends.Generic, Order: StaticMoveOrder, Forced: true, Referee: LookupRefereeKind}
+	first, err := newEngine(config, board)
+	assert.NoError(t, err)
+	second, err := newEngine(config, board)
+	assert.NoError(t, err)
+
+	result := RunTournament(board, first, second, 4, 0)
+
+	assert.Equal(t, 4, result.Games)
+	assert.Equal(t, 4, result.Ties)
+	assert.Equal(t, 9.0, result.AverageGameLength())
+}
+
+func TestTournamentPerfectVersusRandom(t *testing.T) {
+	board := NewBoard(WithSize(3, 3), WithWinStreak(2))
+	first, err := newEngine(&EngineConfig{Name: "perfect", Level: 100, Backend: backends.Generic, Order: ThreatMoveOrder, Forced: true, Referee: LookupRefereeKind}, board)
+	assert.NoError(t, err)
+	second, err := newEngine(&EngineConfig{Name: "random", Level: 0, Backend: backends.Generic, Order: StaticMoveOrder, Forced: true, Referee: LookupRefereeKind}, board)
+	assert.NoError(t, err)
+
+	result := RunTournament(board, first, second, 6, 0)
+
+	assert.Equal(t, 6, result.Games)
+	assert.Equal(t, 3, result.WinsAsA)
+}
+
+func TestTournamentPopOut(t *testing.T) {
+	board := NewBoard(WithSize(3, 3), WithWinStreak(3), WithVariant(PopOutVariant))
+	config := &EngineConfig{Name: "perfect", Level: 100, Backend: backends.Auto, Order: StaticMoveOrder, Forced: true, Referee: LookupRefereeKind}
+	first, err := newEngine(config, board)
+	assert.NoError(t, err)
+	second, err := newEngine(config, board)
+	assert.NoError(t, err)
+
+	result := RunTournament(board, first, second, 4, 0)
+
+	assert.Equal(t, 4, result.Games)
+	assert.Equal(t, 2, result.Wins)
+	assert.Equal(t, 2, result.WinsAsB)
+	assert.Equal(t, 2, result.Losses)
+}
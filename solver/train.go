@@ -9,13 +9,13 @@ import (
 	"github.com/igrek51/connect4solver/solver/common"
 )
 
-func Train(width, height, winStreak int, cacheEnabled bool) {
-	board := common.NewBoard(common.WithSize(width, height), common.WithWinStreak(winStreak))
+func Train(boardOptions []common.Option, cacheEnabled bool, solverOptions ...common.SolverOption) {
+	board := common.NewBoard(boardOptions...)
 	fmt.Println(board.String())
 
-	solver := CreateSolver(board)
-	if cacheEnabled && common.CacheFileExists(board) {
-		common.MustLoadCache(solver.Cache(), board.W, board.H)
+	solver := CreateSolver(board, solverOptions...)
+	if cacheEnabled && CacheFileExists(board) {
+		MustLoadCache(solver.Cache(), board)
 	}
 
 	startTime := time.Now()
@@ -24,9 +24,9 @@ func Train(width, height, winStreak int, cacheEnabled bool) {
 
 	logger := log.New(log.Ctx{
 		"solveTime":   totalElapsed,
-		"boardWidth":  width,
-		"boardHeight": height,
-		"winStreak":   winStreak,
+		"boardWidth":  board.W,
+		"boardHeight": board.H,
+		"winStreak":   board.WinStreak,
 	})
 	logger.Info("Board solved", solver.SummaryVars())
 
@@ -34,15 +34,15 @@ func Train(width, height, winStreak int, cacheEnabled bool) {
 	for move, ending := range endings {
 		if ending != common.NoMove {
 			playerEnding := common.EndingForPlayer(ending, player)
-			log.Info(fmt.Sprintf("Best ending for move %d: %v", move, playerEnding))
+			log.Info(fmt.Sprintf("Best ending for move %s: %s", board.MoveString(move), common.Display.Ending(playerEnding)))
 		}
 	}
 
 	fmt.Println(board.String())
-	printEndingsLine(endings, player)
+	printEndingsLine(board, endings, player)
 
 	if cacheEnabled {
-		common.MustSaveCache(solver.Cache(), board.W, board.H)
+		MustSaveCache(solver.Cache(), board)
 	}
 
 	totalElapsed = time.Since(startTime)
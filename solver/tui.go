@@ -0,0 +1,592 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/igrek51/log15"
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+
+	"github.com/igrek51/connect4solver/solver/common"
+)
+
+// TuiConfig configures the full-screen terminal UI
+type TuiConfig struct {
+	HideA       bool
+	HideB       bool
+	AutoAttackA bool
+	AutoAttackB bool
+	Scores      bool
+	// Browse allows saving the cache from the UI
+	Browse bool
+}
+
+const (
+	tuiFrameDuration = 40 * time.Millisecond
+	tuiHistoryLength = 8
+)
+
+// Tui plays the game in a full-screen terminal UI, the solver runs in the background without blocking input
+func Tui(
+	boardOptions []common.Option,
+	cacheEnabled bool,
+	startWithMoves string,
+	config TuiConfig,
+	solverOptions ...common.SolverOption,
+) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("terminal UI requires an interactive terminal")
+	}
+
+	board := common.NewBoard(boardOptions...)
+	board.ApplyMoves(startWithMoves)
+	solver := CreateSolver(board, solverOptions...)
+	if cacheEnabled && CacheFileExists(board) {
+		if err := LoadCache(solver.Cache(), board); err != nil {
+			return errors.Wrap(err, "loading cache")
+		}
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return errors.Wrap(err, "switching terminal to raw mode")
+	}
+	defer term.Restore(fd, state)
+	// alternate screen buffer keeps the terminal contents, cursor is hidden
+	fmt.Print("\u001b[?1049h\u001b[?25l")
+	defer fmt.Print("\u001b[?25h\u001b[?1049l")
+	if logsToStdout {
+		// logs would break the screen
+		handler := log.Root().GetHandler()
+		log.Root().SetHandler(log.DiscardHandler())
+		defer log.Root().SetHandler(handler)
+	}
+
+	jobs := make(chan tuiJob, 64)
+	results := make(chan tuiResult, 64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for job := range jobs {
+			if job.ctx != nil && job.ctx.Err() != nil {
+				continue // position has changed already
+			}
+			results <- runTuiJob(solver, job, config.Scores)
+		}
+	}()
+	keys := make(chan tuiKey, 16)
+	go readTuiKeys(os.Stdin, keys)
+
+	cancel := context.CancelFunc(func() {})
+	model := newTuiModel(board, solver, config, func(job tuiJob) {
+		if !job.save {
+			cancel()
+			job.ctx, cancel = context.WithCancel(context.Background())
+		}
+		jobs <- job
+	})
+	model.positionChanged()
+
+	ticker := time.NewTicker(tuiFrameDuration)
+	defer ticker.Stop()
+	redraw := true
+	for !model.quit {
+		if redraw {
+			fmt.Print("\u001b[H" + model.render() + "\u001b[J")
+		}
+		redraw = true
+		select {
+		case key := <-keys:
+			model.handleKey(key)
+		case result := <-results:
+			model.handleResult(result)
+		case <-ticker.C:
+			redraw = model.tick()
+		}
+	}
+	cancel()
+	close(jobs)
+	<-done
+	return nil
+}
+
+type tuiAction int
+
+const (
+	tuiLeft tuiAction = iota
+	tuiRight
+	tuiColumn // select and drop to the column
+	tuiDrop
+	tuiPop
+	tuiUndo
+	tuiRedo
+	tuiNew
+	tuiSave
+	tuiQuit
+)
+
+type tuiKey struct {
+	action tuiAction
+	column int
+}
+
+// parseTuiKeys recognizes keys in raw terminal input, unknown bytes are skipped
+func parseTuiKeys(input []byte) []tuiKey {
+	keys := []tuiKey{}
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; {
+		case c == 0x1b && i+2 < len(input) && input[i+1] == '[':
+			switch input[i+2] {
+			case 'C':
+				keys = append(keys, tuiKey{action: tuiRight})
+			case 'D':
+				keys = append(keys, tuiKey{action: tuiLeft})
+			case 'B':
+				keys = append(keys, tuiKey{action: tuiDrop})
+			}
+			i += 2
+		case c >= '0' && c <= '9':
+			keys = append(keys, tuiKey{action: tuiColumn, column: int(c - '0')})
+		case c == '\r' || c == '\n' || c == ' ':
+			keys = append(keys, tuiKey{action: tuiDrop})
+		case c == 'p':
+			keys = append(keys, tuiKey{action: tuiPop})
+		case c == 'u' || c == 0x7f:
+			keys = append(keys, tuiKey{action: tuiUndo})
+		case c == 'r':
+			keys = append(keys, tuiKey{action: tuiRedo})
+		case c == 'n':
+			keys = append(keys, tuiKey{action: tuiNew})
+		case c == 's':
+			keys = append(keys, tuiKey{action: tuiSave})
+		case c == 'q' || c == 0x03 || c == 0x04: // Ctrl+C and Ctrl+D are not signals in raw mode
+			keys = append(keys, tuiKey{action: tuiQuit})
+		}
+	}
+	return keys
+}
+
+func readTuiKeys(in *os.File, keys chan<- tuiKey) {
+	buffer := make([]byte, 64)
+	for {
+		n, err := in.Read(buffer)
+		if err != nil {
+			keys <- tuiKey{action: tuiQuit}
+			return
+		}
+		for _, key := range parseTuiKeys(buffer[:n]) {
+			keys <- key
+		}
+	}
+}
+
+// tuiJob requests analysis of the position or saving the cache
+type tuiJob struct {
+	id    int
+	ctx   context.Context
+	board *common.Board
+	auto  bool
+	save  bool
+}
+
+type tuiResult struct {
+	id      int
+	endings []common.Player
+	scores  []int
+	err     error
+	elapsed time.Duration
+	// message is a status to show, eg. after saving
+	message string
+}
+
+// runTuiJob solves endings and scores of the moves, it runs in the background goroutine owning the solver
+func runTuiJob(solver common.IMoveSolver, job tuiJob, scoresEnabled bool) tuiResult {
+	if job.save {
+		if err := SaveCache(solver.Cache(), job.board); err != nil {
+			return tuiResult{id: job.id, message: fmt.Sprintf("Saving cache failed: %v", err)}
+		}
+		return tuiResult{id: job.id, message: "Cache saved"}
+	}
+	startTime := time.Now()
+	endings, err := solvePlayEndings(job.ctx, solver, job.board, job.auto)
+	if err != nil && job.ctx.Err() != nil {
+		return tuiResult{id: job.id, err: err}
+	}
+	player := job.board.NextPlayer()
+	scores := estimateMoveScores(solver, endings, player, job.board, scoresEnabled)
+	return tuiResult{id: job.id, endings: endings, scores: scores, err: err, elapsed: time.Since(startTime)}
+}
+
+// tuiFalling is a token dropping down the column
+type tuiFalling struct {
+	x       int
+	y       int
+	targetY int
+}
+
+// tuiModel keeps the state of the terminal UI, it's changed only by the UI goroutine
+type tuiModel struct {
+	config TuiConfig
+	solver common.IMoveSolver
+	start  *common.Board
+	game   *common.Game
+	board  *common.Board
+
+	selected   int
+	positionID int
+	solveStart time.Time
+	// analysis of the current position, nil while it's being solved
+	analysis *tuiResult
+	falling  *tuiFalling
+	over     bool
+	winner   common.Player
+	message  string
+	quit     bool
+
+	// request sends the job to the background solver
+	request func(job tuiJob)
+}
+
+func newTuiModel(
+	board *common.Board, solver common.IMoveSolver, config TuiConfig, request func(job tuiJob),
+) *tuiModel {
+	m := &tuiModel{
+		config:   config,
+		solver:   solver,
+		start:    board.Clone(),
+		game:     common.NewGame(board),
+		board:    board,
+		selected: board.W / 2,
+		winner:   common.Empty,
+		request:  request,
+	}
+	return m
+}
+
+func (m *tuiModel) isAuto(player common.Player) bool {
+	return (player == common.PlayerA && m.config.AutoAttackA) || (player == common.PlayerB && m.config.AutoAttackB)
+}
+
+func (m *tuiModel) showHints(player common.Player) bool {
+	return (player == common.PlayerA && !m.config.HideA) || (player == common.PlayerB && !m.config.HideB)
+}
+
+// positionChanged starts solving the current position in the background
+func (m *tuiModel) positionChanged() {
+	m.positionID++
+	m.analysis = nil
+	m.solveStart = time.Now()
+	if m.over {
+		return
+	}
+	player := m.board.NextPlayer()
+	m.request(tuiJob{id: m.positionID, board: m.board.Clone(), auto: m.isAuto(player)})
+}
+
+func (m *tuiModel) handleKey(key tuiKey) {
+	m.message = ""
+	switch key.action {
+	case tuiQuit:
+		m.quit = true
+	case tuiLeft:
+		m.selected = (m.selected + m.board.W - 1) % m.board.W
+	case tuiRight:
+		m.selected = (m.selected + 1) % m.board.W
+	case tuiColumn:
+		if key.column >= m.board.W {
+			m.message = "Column is out of range"
+			return
+		}
+		m.selected = key.column
+		m.playHuman(key.column)
+	case tuiDrop:
+		m.playHuman(m.selected)
+	case tuiPop:
+		if m.board.Variant != common.PopOutVariant {
+			m.message = "Popping out is allowed only in PopOut variant"
+			return
+		}
+		m.playHuman(m.board.PopMove(m.selected))
+	case tuiUndo:
+		m.undo()
+	case tuiRedo:
+		m.redo()
+	case tuiNew:
+		m.newGame()
+	case tuiSave:
+		if !m.config.Browse {
+			return
+		}
+		m.message = "Saving cache..."
+		m.request(tuiJob{id: m.positionID, board: m.board.Clone(), save: true})
+	}
+}
+
+func (m *tuiModel) playHuman(move int) {
+	player := m.board.NextPlayer()
+	if m.over || m.isAuto(player) {
+		return
+	}
+	if !m.board.CanPlay(move, player) {
+		if m.board.IsPopMove(move) {
+			m.message = "Can't pop out from the column"
+		} else {
+			m.message = "Column is already full"
+		}
+		return
+	}
+	m.play(move)
+}
+
+func (m *tuiModel) play(move int) {
+	m.finishFalling()
+	ending := common.NoMove
+	if m.analysis != nil {
+		ending = m.analysis.endings[move]
+	}
+	gameMove := m.game.Play(move, ending)
+	if !m.board.IsPopMove(move) {
+		m.falling = &tuiFalling{x: move, y: m.board.Heights[move] - 1, targetY: gameMove.Y}
+	}
+	m.checkGameOver()
+	m.positionChanged()
+}
+
+func (m *tuiModel) undo() {
+	m.finishFalling()
+	if !undoTurn(m.game, m.isAuto) {
+		m.message = "Nothing to undo"
+		return
+	}
+	m.checkGameOver()
+	m.positionChanged()
+}
+
+func (m *tuiModel) redo() {
+	m.finishFalling()
+	if !redoTurn(m.game, m.isAuto) {
+		m.message = "Nothing to redo"
+		return
+	}
+	m.checkGameOver()
+	m.positionChanged()
+}
+
+// checkGameOver finds out if the last move has finished the game
+func (m *tuiModel) checkGameOver() {
+	m.over = false
+	m.winner = common.Empty
+	last, ok := m.game.LastMove()
+	if !ok {
+		return
+	}
+	if winner := moveWinner(m.solver, m.board, last.Move, last.Y, last.Player); winner != common.Empty {
+		m.over = true
+		m.winner = winner
+	} else if isATie(m.board) || m.game.Repetitions() >= common.MaxRepetitions {
+		m.over = true
+	}
+}
+
+func (m *tuiModel) newGame() {
+	m.finishFalling()
+	m.board = m.start.Clone()
+	m.game = common.NewGame(m.board)
+	m.over = false
+	m.winner = common.Empty
+	m.positionChanged()
+}
+
+func (m *tuiModel) handleResult(result tuiResult) {
+	if result.message != "" {
+		m.message = result.message
+		return
+	}
+	if result.id != m.positionID {
+		return // position has changed in the meantime
+	}
+	m.analysis = &result
+	m.autoMove()
+}
+
+// autoMove plays the best move for the computer player, once the position is solved and the animation is over
+func (m *tuiModel) autoMove() {
+	player := m.board.NextPlayer()
+	if m.over || m.analysis == nil || m.falling != nil || !m.isAuto(player) {
+		return
+	}
+	move := findBestMove(m.board, m.analysis.scores)
+	if move < 0 {
+		m.message = "There is no move to play"
+		return
+	}
+	m.play(move)
+}
+
+// tick moves the animation on, it tells if the screen has changed
+func (m *tuiModel) tick() bool {
+	if m.falling == nil {
+		// solving time is ticking
+		return !m.over && m.analysis == nil
+	}
+	m.falling.y--
+	if m.falling.y <= m.falling.targetY {
+		m.falling = nil
+		m.autoMove()
+	}
+	return true
+}
+
+func (m *tuiModel) finishFalling() {
+	m.falling = nil
+}
+
+// render draws the board with the side panel, overwriting the previous screen line by line.
+// Lines are ended with CR, as the terminal is in raw mode.
+func (m *tuiModel) render() string {
+	boardLines := m.boardLines()
+	panelLines := m.panelLines()
+	boardWidth := 2*m.board.W + 3
+	lines := []string{}
+	for i := 0; i < len(boardLines) || i < len(panelLines); i++ {
+		line := strings.Repeat(" ", boardWidth)
+		if i < len(boardLines) {
+			line = boardLines[i]
+		}
+		if i < len(panelLines) {
+			line += "   " + panelLines[i]
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\u001b[K\r\n") + "\u001b[K\r\n"
+}
+
+func (m *tuiModel) boardLines() []string {
+	display := common.Display
+	player := m.board.NextPlayer()
+	markers := make([]string, m.board.W)
+	for x := range markers {
+		markers[x] = " "
+	}
+	if !m.over && !m.isAuto(player) {
+		markers[m.selected] = display.Player(player)
+	}
+	lines := []string{"  " + strings.Join(markers, " ") + "  "}
+
+	boardStr := display.BoardCells(m.board, func(x, y int, cell common.Player) string {
+		if m.falling != nil && x == m.falling.x {
+			if y == m.falling.y {
+				return display.Highlight(display.Player(m.board.GetCell(x, m.falling.targetY)))
+			}
+			if y == m.falling.targetY {
+				return display.Player(common.Empty)
+			}
+		}
+		return display.Player(cell)
+	})
+	return append(lines, strings.Split(boardStr, "\n")...)
+}
+
+func (m *tuiModel) panelLines() []string {
+	display := common.Display
+	player := m.board.NextPlayer()
+	depth := m.board.CountMoves()
+	lines := []string{}
+
+	if m.over && m.winner != common.Empty {
+		lines = append(lines, fmt.Sprintf("Player %s won in %d moves", display.Player(m.winner), depth))
+	} else if m.over {
+		lines = append(lines, fmt.Sprintf("%s in %d moves", display.Ending(common.Tie), depth))
+	} else if m.isAuto(player) {
+		lines = append(lines, fmt.Sprintf("Player %s is thinking", display.Player(player)))
+	} else {
+		lines = append(lines, fmt.Sprintf("Player %s to move", display.Player(player)))
+	}
+
+	if m.over {
+		lines = append(lines, "")
+	} else if m.analysis == nil {
+		elapsed := time.Since(m.solveStart).Truncate(100 * time.Millisecond)
+		lines = append(lines, fmt.Sprintf("Solving... %v", elapsed))
+	} else if m.analysis.err != nil {
+		lines = append(lines, fmt.Sprintf("Solving failed: %v", m.analysis.err))
+	} else {
+		elapsed := m.analysis.elapsed.Round(time.Millisecond)
+		if elapsed == 0 {
+			elapsed = m.analysis.elapsed.Round(time.Microsecond)
+		}
+		lines = append(lines, fmt.Sprintf("Solved in %v", elapsed))
+	}
+	lines = append(lines, "")
+	lines = append(lines, m.hintLines(player)...)
+	lines = append(lines, "")
+	lines = append(lines, m.historyLines()...)
+	lines = append(lines, "")
+
+	keys := "←/→ select, enter drop"
+	if m.board.Variant == common.PopOutVariant {
+		keys += ", p pop"
+	}
+	keys += ", u undo, r redo, n new"
+	if m.config.Browse {
+		keys += ", s save"
+	}
+	lines = append(lines, keys+", q quit")
+	lines = append(lines, m.message)
+	return lines
+}
+
+// hintLines show endings and scores of each move for the player to move
+func (m *tuiModel) hintLines(player common.Player) []string {
+	display := common.Display
+	header := "Column "
+	for x := 0; x < m.board.W; x++ {
+		header += fmt.Sprintf("%4d", x)
+	}
+	lines := []string{header}
+	if m.over || m.analysis == nil || !m.showHints(player) {
+		return lines
+	}
+	for start := 0; start < len(m.analysis.endings); start += m.board.W {
+		endingsLine := "Ending "
+		scoresLine := "Score  "
+		if start > 0 {
+			endingsLine = "Pop    "
+			scoresLine = "Pop sc."
+		}
+		for move := start; move < start+m.board.W; move++ {
+			ending := m.analysis.endings[move]
+			if ending == common.NoMove {
+				endingsLine += "   " + display.ShortEnding(common.NoEnding)
+				scoresLine += "    "
+				continue
+			}
+			endingsLine += "   " + display.ShortEnding(common.EndingForPlayer(ending, player))
+			scoresLine += fmt.Sprintf("%4d", m.analysis.scores[move])
+		}
+		lines = append(lines, endingsLine)
+		if m.config.Scores {
+			lines = append(lines, scoresLine)
+		}
+	}
+	return lines
+}
+
+// historyLines show the latest moves with the endings they lead to according to the solver
+func (m *tuiModel) historyLines() []string {
+	moves := m.game.Moves()
+	lines := []string{fmt.Sprintf("Moves: %d", len(moves))}
+	from := len(moves) - tuiHistoryLength
+	if from < 0 {
+		from = 0
+	}
+	for ply := from; ply < len(moves); ply++ {
+		lines = append(lines, gameMoveString(m.board, ply, moves[ply]))
+	}
+	return lines
+}
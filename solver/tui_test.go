@@ -0,0 +1,138 @@
+package solver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/igrek51/connect4solver/solver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTuiKeys(t *testing.T) {
+	keys := parseTuiKeys([]byte("\u001b[C\u001b[D\u001b[A3 \rpurq\u0003x"))
+	assert.Equal(t, []tuiKey{
+		{action: tuiRight},
+		{action: tuiLeft},
+		{action: tuiColumn, column: 3},
+		{action: tuiDrop},
+		{action: tuiDrop},
+		{action: tuiPop},
+		{action: tuiUndo},
+		{action: tuiRedo},
+		{action: tuiQuit},
+		{action: tuiQuit},
+	}, keys)
+}
+
+// newTestTuiModel creates model solving the requested positions synchronously
+func newTestTuiModel(board *Board, config TuiConfig) *tuiModel {
+	solver := CreateSolver(board)
+	m := newTuiModel(board, solver, config, nil)
+	m.request = func(job tuiJob) {
+		job.ctx = context.Background()
+		m.handleResult(runTuiJob(solver, job, config.Scores))
+	}
+	return m
+}
+
+func TestTuiDropAndUndo(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3))
+	m := newTestTuiModel(board, TuiConfig{})
+	m.positionChanged()
+	assert.NotNil(t, m.analysis)
+	assert.Equal(t, 2, m.selected)
+
+	m.handleKey(tuiKey{action: tuiRight})
+	m.handleKey(tuiKey{action: tuiDrop})
+	assert.Equal(t, PlayerA, board.GetCell(3, 0))
+	assert.Len(t, m.game.Moves(), 1)
+	assert.NotNil(t, m.falling)
+	assert.Contains(t, m.render(), "Player B to move")
+	for m.tick() && m.falling != nil {
+	}
+	assert.Nil(t, m.falling)
+
+	m.handleKey(tuiKey{action: tuiColumn, column: 9})
+	assert.Equal(t, "Column is out of range", m.message)
+	m.handleKey(tuiKey{action: tuiUndo})
+	assert.Empty(t, m.game.Moves())
+	assert.Equal(t, Empty, board.GetCell(3, 0))
+	assert.Equal(t, 1, m.game.Repetitions())
+	assert.Contains(t, m.render(), "Player A to move")
+
+	m.handleKey(tuiKey{action: tuiRedo})
+	assert.Len(t, m.game.Moves(), 1)
+	assert.Equal(t, PlayerA, board.GetCell(3, 0))
+	m.handleKey(tuiKey{action: tuiRedo})
+	assert.Equal(t, "Nothing to redo", m.message)
+}
+
+func TestTuiAutoAttack(t *testing.T) {
+	board := NewBoard(WithSize(4, 4), WithWinStreak(3))
+	m := newTestTuiModel(board, TuiConfig{AutoAttackB: true})
+	m.positionChanged()
+	m.handleKey(tuiKey{action: tuiColumn, column: 0})
+	// computer moves once the token has fallen
+	assert.Len(t, m.game.Moves(), 1)
+	for m.falling != nil {
+		m.tick()
+	}
+	assert.Len(t, m.game.Moves(), 2)
+	assert.Equal(t, PlayerB, m.game.Moves()[1].Player)
+
+	// human's move is undone together with the computer's one
+	m.handleKey(tuiKey{action: tuiUndo})
+	assert.Empty(t, m.game.Moves())
+	assert.Equal(t, PlayerA, board.NextPlayer())
+	m.handleKey(tuiKey{action: tuiRedo})
+	assert.Len(t, m.game.Moves(), 2)
+	assert.Equal(t, PlayerA, board.NextPlayer())
+}
+
+func TestTuiAutoMoveWithoutEndings(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		// PopOut board is too big to be solved, so none of the endings is known
+		board := NewBoard(WithSize(7, 6), WithVariant(PopOutVariant)).ApplyMoves("000000")
+		playable := map[int]bool{}
+		for move := 0; move < board.MoveSlots(); move++ {
+			playable[move] = board.CanPlay(move, PlayerA)
+		}
+		m := newTestTuiModel(board, TuiConfig{AutoAttackA: true})
+		m.positionChanged()
+		assert.Error(t, m.analysis.err)
+		last, ok := m.game.LastMove()
+		if assert.True(t, ok) {
+			assert.True(t, playable[last.Move], "move %d is not playable", last.Move)
+		}
+	}
+}
+
+func TestTuiGameOver(t *testing.T) {
+	board := ParseBoard(`
+	....
+	....
+	A...
+	AB.B
+	`, WithWinStreak(3))
+	m := newTestTuiModel(board, TuiConfig{Scores: true})
+	m.positionChanged()
+	screen := m.render()
+	assert.Contains(t, screen, "Ending    W")
+	assert.Contains(t, screen, "Score   100")
+
+	m.handleKey(tuiKey{action: tuiColumn, column: 0})
+	assert.True(t, m.over)
+	assert.Equal(t, PlayerA, m.winner)
+	screen = m.render()
+	assert.Contains(t, screen, "Player A won in 5 moves")
+	assert.Contains(t, screen, "  1. A 0  Win")
+
+	m.handleKey(tuiKey{action: tuiColumn, column: 1})
+	assert.Len(t, m.game.Moves(), 1)
+	m.handleKey(tuiKey{action: tuiNew})
+	assert.False(t, m.over)
+	assert.Empty(t, m.game.Moves())
+	assert.Equal(t, uint(4), m.board.CountMoves())
+	assert.True(t, strings.HasSuffix(m.render(), "\u001b[K\r\n"))
+}